@@ -0,0 +1,10 @@
+ALTER TABLE notes DROP COLUMN IF EXISTS search_vector;
+ALTER TABLE notes
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('simple', coalesce(content, '')), 'B')
+    ) STORED;
+CREATE INDEX idx_notes_search_vector ON notes USING GIN (search_vector);
+
+ALTER TABLE notes DROP COLUMN IF EXISTS language;
+DROP FUNCTION IF EXISTS note_search_config(TEXT);
@@ -0,0 +1,19 @@
+CREATE OR REPLACE FUNCTION note_search_config(lang TEXT) RETURNS regconfig AS $$
+    SELECT CASE lang
+        WHEN 'es' THEN 'spanish'::regconfig
+        WHEN 'en' THEN 'english'::regconfig
+        WHEN 'it' THEN 'italian'::regconfig
+        ELSE 'simple'::regconfig
+    END
+$$ LANGUAGE SQL IMMUTABLE;
+
+ALTER TABLE notes ADD COLUMN language VARCHAR(2) NOT NULL DEFAULT '';
+
+ALTER TABLE notes DROP COLUMN search_vector;
+ALTER TABLE notes
+    ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+        setweight(to_tsvector(note_search_config(language), coalesce(title, '')), 'A') ||
+        setweight(to_tsvector(note_search_config(language), coalesce(content, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_notes_search_vector ON notes USING GIN (search_vector);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_notes_deleted_at;
+ALTER TABLE notes DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS revision_retention;
+DROP TABLE IF EXISTS note_revisions CASCADE;
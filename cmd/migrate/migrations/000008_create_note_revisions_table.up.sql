@@ -0,0 +1,11 @@
+CREATE TABLE note_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    note_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    revision INTEGER NOT NULL,
+    title VARCHAR(500) NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE(note_id, revision)
+);
+
+ALTER TABLE users ADD COLUMN revision_retention INTEGER NOT NULL DEFAULT 50;
@@ -0,0 +1,18 @@
+CREATE TABLE note_links (
+    source_id UUID NOT NULL REFERENCES notes(id) ON DELETE CASCADE,
+    target TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (source_id, target)
+);
+
+CREATE INDEX idx_note_links_target ON note_links (lower(target));
+
+INSERT INTO note_links (source_id, target, position)
+SELECT DISTINCT ON (source_id, lower(target)) source_id, target, position
+FROM (
+    SELECT n.id AS source_id, trim(split_part(m.match[1], '|', 1)) AS target, m.position
+    FROM notes n
+    CROSS JOIN LATERAL regexp_matches(n.content, '\[\[([^\[\]\n]+)\]\]', 'g') WITH ORDINALITY AS m(match, position)
+) links
+WHERE target <> ''
+ORDER BY source_id, lower(target), position;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notes_user_order;
+ALTER TABLE notes DROP COLUMN IF EXISTS position;
+ALTER TABLE notes DROP COLUMN IF EXISTS pinned;
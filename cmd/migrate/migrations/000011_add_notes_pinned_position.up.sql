@@ -0,0 +1,12 @@
+ALTER TABLE notes ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE notes ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+UPDATE notes n
+SET position = ordered.position
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY updated_at DESC) AS position
+    FROM notes
+) ordered
+WHERE ordered.id = n.id;
+
+CREATE INDEX idx_notes_user_order ON notes(user_id, pinned DESC, position);
@@ -0,0 +1,16 @@
+CREATE TABLE notebooks (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    parent_id UUID REFERENCES notebooks(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    UNIQUE NULLS NOT DISTINCT (user_id, parent_id, name)
+);
+
+CREATE INDEX idx_notebooks_user_id ON notebooks(user_id);
+CREATE INDEX idx_notebooks_parent_id ON notebooks(parent_id);
+
+ALTER TABLE notes ADD COLUMN notebook_id UUID REFERENCES notebooks(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_notes_notebook_id ON notes(notebook_id);
@@ -0,0 +1,17 @@
+ALTER TABLE tags ADD COLUMN parent_id UUID REFERENCES tags(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_tags_parent_id ON tags(parent_id);
+
+-- Existing path names get their missing ancestors and are linked to them.
+INSERT INTO tags (user_id, name)
+SELECT DISTINCT t.user_id, array_to_string((string_to_array(t.name, '/'))[1:depth.n], '/')
+FROM tags t
+CROSS JOIN LATERAL generate_series(1, cardinality(string_to_array(t.name, '/')) - 1) AS depth(n)
+ON CONFLICT (user_id, name) DO NOTHING;
+
+UPDATE tags child
+SET parent_id = parent.id
+FROM tags parent
+WHERE parent.user_id = child.user_id
+AND position('/' IN child.name) > 0
+AND parent.name = regexp_replace(child.name, '/[^/]*$', '');
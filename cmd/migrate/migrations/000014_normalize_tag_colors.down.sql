@@ -0,0 +1 @@
+ALTER TABLE tags ALTER COLUMN color SET DEFAULT '#fff';
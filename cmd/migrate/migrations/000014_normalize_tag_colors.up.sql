@@ -0,0 +1,8 @@
+-- Colors are stored as lowercase #rrggbb, which is what the app writes now.
+UPDATE tags
+SET color = '#' || repeat(substr(color, 2, 1), 2) || repeat(substr(color, 3, 1), 2) || repeat(substr(color, 4, 1), 2)
+WHERE color ~ '^#[0-9A-Fa-f]{3}$';
+
+UPDATE tags SET color = lower(color) WHERE color <> lower(color);
+
+ALTER TABLE tags ALTER COLUMN color SET DEFAULT '#ffffff';
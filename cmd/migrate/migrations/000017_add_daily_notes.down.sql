@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS daily_template_id;
+ALTER TABLE notes DROP CONSTRAINT IF EXISTS notes_user_id_daily_date_key;
+ALTER TABLE notes DROP COLUMN IF EXISTS daily_date;
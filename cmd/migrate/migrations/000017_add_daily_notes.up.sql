@@ -0,0 +1,6 @@
+ALTER TABLE notes ADD COLUMN daily_date DATE;
+
+-- NULLs stay distinct, so only daily notes are limited to one per day.
+ALTER TABLE notes ADD CONSTRAINT notes_user_id_daily_date_key UNIQUE (user_id, daily_date);
+
+ALTER TABLE users ADD COLUMN daily_template_id UUID REFERENCES note_templates(id) ON DELETE SET NULL;
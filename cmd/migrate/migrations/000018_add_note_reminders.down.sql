@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_notes_pending_reminders;
+ALTER TABLE notes DROP COLUMN IF EXISTS reminded_at;
+ALTER TABLE notes DROP COLUMN IF EXISTS remind_at;
+ALTER TABLE notes DROP COLUMN IF EXISTS due_at;
+ALTER TABLE users DROP COLUMN IF EXISTS timezone;
@@ -0,0 +1,8 @@
+ALTER TABLE users ADD COLUMN timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+
+ALTER TABLE notes ADD COLUMN due_at TIMESTAMPTZ;
+ALTER TABLE notes ADD COLUMN remind_at TIMESTAMPTZ;
+ALTER TABLE notes ADD COLUMN reminded_at TIMESTAMPTZ;
+
+-- The scheduler only ever looks for reminders that have not fired yet.
+CREATE INDEX idx_notes_pending_reminders ON notes(remind_at) WHERE remind_at IS NOT NULL AND reminded_at IS NULL;
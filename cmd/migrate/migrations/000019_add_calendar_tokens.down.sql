@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_notes_scheduled;
+ALTER TABLE users DROP COLUMN IF EXISTS calendar_token_hash;
@@ -0,0 +1,5 @@
+-- Only the SHA-256 of the calendar feed token is kept; NULL means the feed
+-- is off.
+ALTER TABLE users ADD COLUMN calendar_token_hash VARCHAR(64) UNIQUE;
+
+CREATE INDEX idx_notes_scheduled ON notes(user_id, due_at, remind_at) WHERE due_at IS NOT NULL OR remind_at IS NOT NULL;
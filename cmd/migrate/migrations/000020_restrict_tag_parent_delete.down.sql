@@ -0,0 +1,2 @@
+ALTER TABLE tags DROP CONSTRAINT tags_parent_id_fkey;
+ALTER TABLE tags ADD CONSTRAINT tags_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES tags(id) ON DELETE CASCADE;
@@ -0,0 +1,3 @@
+-- A tag with children can no longer be deleted; they used to go with it.
+ALTER TABLE tags DROP CONSTRAINT tags_parent_id_fkey;
+ALTER TABLE tags ADD CONSTRAINT tags_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES tags(id);
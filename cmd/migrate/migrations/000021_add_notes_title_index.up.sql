@@ -0,0 +1,2 @@
+-- Wiki links are resolved by case-insensitive title.
+CREATE INDEX idx_notes_user_lower_title ON notes (user_id, lower(title));
@@ -2,12 +2,15 @@ package main
 
 import (
 	"context"
+	_ "time/tzdata"
 
 	"go.uber.org/zap"
 
 	"github.com/manuelmtzv/mangocatnotes-api/internal/config"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/db"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/jobs"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/kvstore"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/markdown"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/server"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/session"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
@@ -43,7 +46,20 @@ func main() {
 
 	session := session.NewSessionManager(cache)
 
-	s := server.New(cfg, logger, store.NewStorage(database.Pool), session)
+	storage := store.NewStorage(database.Pool)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	purger := jobs.NewTrashPurger(storage.Notes, logger, cfg.TrashRetention, cfg.TrashPurgeInterval)
+	go purger.Run(ctx)
+
+	reminders := jobs.NewReminderScheduler(storage.Notes, jobs.NewLogNotifier(logger), logger, cfg.ReminderInterval, cfg.ReminderBatchSize)
+	go reminders.Run(ctx)
+
+	renderer := markdown.NewRenderer(cache)
+
+	s := server.New(cfg, logger, storage, session, renderer)
 
 	if err := s.Start(); err != nil {
 		logger.Fatalw("Server failed", "error", err)
@@ -0,0 +1,108 @@
+package checklist
+
+import (
+	"regexp"
+	"strings"
+)
+
+// taskPattern matches a GFM task list item, also inside block quotes. The
+// groups are the list marker, the box state and the item text.
+var taskPattern = regexp.MustCompile(`^((?:[ \t]*>)*[ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+)\[([ xX])\][ \t]+(\S.*)$`)
+
+type Item struct {
+	Index   int    `json:"index"`
+	Text    string `json:"text"`
+	Checked bool   `json:"checked"`
+}
+
+// Checklist holds the task list items of a note in the order they appear,
+// which is also the order the rendered checkboxes come in.
+type Checklist struct {
+	Items []Item `json:"items"`
+	Done  int    `json:"done"`
+	Total int    `json:"total"`
+}
+
+// Parse returns the task list items in content, skipping fenced code.
+func Parse(content string) Checklist {
+	list := Checklist{Items: []Item{}}
+	eachTask(strings.Split(content, "\n"), func(line int, match []string) bool {
+		item := Item{
+			Index:   list.Total,
+			Text:    strings.TrimSpace(match[3]),
+			Checked: match[2] != " ",
+		}
+		list.Items = append(list.Items, item)
+		list.Total++
+		if item.Checked {
+			list.Done++
+		}
+		return true
+	})
+	return list
+}
+
+// Set checks or unchecks the item at index, reporting false when content has
+// no such item.
+func Set(content string, index int, checked bool) (string, bool) {
+	lines := strings.Split(content, "\n")
+	found := false
+
+	current := 0
+	eachTask(lines, func(line int, match []string) bool {
+		if current < index {
+			current++
+			return true
+		}
+
+		box := " "
+		if checked {
+			box = "x"
+		}
+		rest := strings.TrimPrefix(lines[line], match[1]+"["+match[2]+"]")
+		lines[line] = match[1] + "[" + box + "]" + rest
+		found = true
+		return false
+	})
+
+	return strings.Join(lines, "\n"), found
+}
+
+// eachTask calls fn with every task list line outside fenced code blocks
+// until fn returns false.
+func eachTask(lines []string, fn func(line int, match []string) bool) {
+	fence := ""
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(strings.TrimRight(line, "\r"))
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "" {
+				fence = ""
+			}
+			continue
+		}
+		if marker := fenceMarker(trimmed); marker != "" {
+			fence = marker
+			continue
+		}
+
+		match := taskPattern.FindStringSubmatch(strings.TrimRight(line, "\r"))
+		if match == nil {
+			continue
+		}
+		if !fn(i, match) {
+			return
+		}
+	}
+}
+
+// fenceMarker returns the run of backticks or tildes opening a fenced code
+// block, or "" when the line does not open one.
+func fenceMarker(line string) string {
+	for _, char := range []string{"`", "~"} {
+		if strings.HasPrefix(line, char+char+char) {
+			return strings.Repeat(char, len(line)-len(strings.TrimLeft(line, char)))
+		}
+	}
+	return ""
+}
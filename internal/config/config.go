@@ -1,6 +1,9 @@
 package config
 
 import (
+	"log"
+	"time"
+
 	"github.com/manuelmtzv/mangocatnotes-api/internal/env"
 )
 
@@ -18,12 +21,16 @@ type Config struct {
 	IsProd               bool
 	AllowedOrigins       []string
 	GAID                 string
+	TrashRetention       time.Duration
+	TrashPurgeInterval   time.Duration
+	ReminderInterval     time.Duration
+	ReminderBatchSize    int
 }
 
 func LoadConfig() *Config {
 	env.Load()
 
-	return &Config{
+	cfg := &Config{
 		Port:                 env.GetString("PORT", "3000"),
 		DBAddr:               env.GetRequired("DB_ADDR"),
 		MaxOpenConns:         env.GetInt("DB_MAX_OPEN_CONNS", 30),
@@ -37,5 +44,23 @@ func LoadConfig() *Config {
 		IsProd:               env.GetBool("IS_PROD", false),
 		AllowedOrigins:       env.GetSlice("ALLOWED_ORIGINS", []string{"http://localhost:3000"}),
 		GAID:                 env.GetString("GA_ID", ""),
+		TrashRetention:       env.GetDuration("TRASH_RETENTION", 30*24*time.Hour),
+		TrashPurgeInterval:   env.GetDuration("TRASH_PURGE_INTERVAL", time.Hour),
+		ReminderInterval:     env.GetDuration("REMINDER_INTERVAL", time.Minute),
+		ReminderBatchSize:    env.GetInt("REMINDER_BATCH_SIZE", 100),
+	}
+
+	// The background jobs tick on these intervals and claim reminders in
+	// batches of this size, none of which work with zero or less.
+	if cfg.TrashPurgeInterval <= 0 {
+		log.Fatalf("TRASH_PURGE_INTERVAL must be positive, got %s", cfg.TrashPurgeInterval)
 	}
+	if cfg.ReminderInterval <= 0 {
+		log.Fatalf("REMINDER_INTERVAL must be positive, got %s", cfg.ReminderInterval)
+	}
+	if cfg.ReminderBatchSize <= 0 {
+		log.Fatalf("REMINDER_BATCH_SIZE must be positive, got %d", cfg.ReminderBatchSize)
+	}
+
+	return cfg
 }
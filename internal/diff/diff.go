@@ -0,0 +1,146 @@
+package diff
+
+import "strings"
+
+type Op string
+
+const (
+	Equal  Op = "equal"
+	Insert Op = "insert"
+	Delete Op = "delete"
+)
+
+type Line struct {
+	Op   Op     `json:"op"`
+	Text string `json:"text"`
+}
+
+// Lines returns the shortest line-based edit script that turns a into b,
+// using Myers' algorithm.
+func Lines(a, b string) []Line {
+	return diff(splitLines(a), splitLines(b))
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
+
+func diff(a, b []string) []Line {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]Line, 0, len(a)+len(b))
+	for _, text := range a[:prefix] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	lines = append(lines, myers(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])...)
+	for _, text := range a[len(a)-suffix:] {
+		lines = append(lines, Line{Op: Equal, Text: text})
+	}
+	return lines
+}
+
+// frontier holds the furthest x reached on each diagonal k for one value of
+// d. Only diagonals -d..d are kept, so the trace grows with D² rather than
+// D·(N+M).
+type frontier struct {
+	d int
+	x []int
+}
+
+func (f frontier) get(k int) int {
+	i := k + f.d + 1
+	if i < 0 || i >= len(f.x) {
+		return 0
+	}
+	return f.x[i]
+}
+
+func myers(a, b []string) []Line {
+	n, m := len(a), len(b)
+	if n == 0 && m == 0 {
+		return nil
+	}
+
+	var trace []frontier
+	prev := frontier{d: 0, x: []int{0, 0, 0}}
+
+	for d := 0; d <= n+m; d++ {
+		cur := frontier{d: d, x: make([]int, 2*d+3)}
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && prev.get(k-1) < prev.get(k+1)) {
+				x = prev.get(k + 1)
+			} else {
+				x = prev.get(k-1) + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			cur.x[k+d+1] = x
+
+			if x >= n && y >= m {
+				trace = append(trace, prev)
+				return backtrack(trace, a, b)
+			}
+		}
+		trace = append(trace, prev)
+		prev = cur
+	}
+
+	return nil
+}
+
+// backtrack walks the trace from the end, where trace[d] holds the frontier
+// reached after d-1 edits.
+func backtrack(trace []frontier, a, b []string) []Line {
+	x, y := len(a), len(b)
+	var reversed []Line
+
+	for d := len(trace) - 1; d >= 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		var prevK int
+		if k == -d || (k != d && v.get(k-1) < v.get(k+1)) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := v.get(prevK)
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			reversed = append(reversed, Line{Op: Equal, Text: a[x-1]})
+			x--
+			y--
+		}
+
+		if d > 0 {
+			if x == prevX {
+				reversed = append(reversed, Line{Op: Insert, Text: b[y-1]})
+			} else {
+				reversed = append(reversed, Line{Op: Delete, Text: a[x-1]})
+			}
+		}
+
+		x, y = prevX, prevY
+	}
+
+	lines := make([]Line, len(reversed))
+	for i, line := range reversed {
+		lines[len(reversed)-1-i] = line
+	}
+	return lines
+}
@@ -0,0 +1,98 @@
+package diff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func eq(text string) Line  { return Line{Op: Equal, Text: text} }
+func ins(text string) Line { return Line{Op: Insert, Text: text} }
+func del(text string) Line { return Line{Op: Delete, Text: text} }
+
+func TestLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{"both empty", "", "", []Line{}},
+		{"unchanged", "a\nb\n", "a\nb\n", []Line{eq("a"), eq("b")}},
+		{"all inserted", "", "a\nb", []Line{ins("a"), ins("b")}},
+		{"all deleted", "a\nb", "", []Line{del("a"), del("b")}},
+		{"trailing newline ignored", "a\nb\n", "a\nb", []Line{eq("a"), eq("b")}},
+		{"blank last line kept", "a\n\n", "a\n", []Line{eq("a"), del("")}},
+		{"only a newline", "\n", "", []Line{del("")}},
+		{"insert in the middle", "a\nc", "a\nb\nc", []Line{eq("a"), ins("b"), eq("c")}},
+		{"delete in the middle", "a\nb\nc", "a\nc", []Line{eq("a"), del("b"), eq("c")}},
+		{"replace a line", "a\nb\nc", "a\nx\nc", []Line{eq("a"), del("b"), ins("x"), eq("c")}},
+		{"replace everything", "a\nb", "x\ny", []Line{del("a"), del("b"), ins("x"), ins("y")}},
+		{"append", "a", "a\nb", []Line{eq("a"), ins("b")}},
+		{"prepend", "b", "a\nb", []Line{ins("a"), eq("b")}},
+		{"move a line", "a\nb\nc", "b\nc\na", []Line{del("a"), eq("b"), eq("c"), ins("a")}},
+		{"repeated lines", "a\na\nb", "a\nb\nb", []Line{eq("a"), del("a"), ins("b"), eq("b")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Lines(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Lines(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestLinesShortest checks that the script rebuilds both sides and makes no
+// more edits than a longest common subsequence allows.
+func TestLinesShortest(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"a\nb\nc\na\nb\nb\na", "c\nb\na\nb\na\nc"},
+		{"x\ny\nz", "z\ny\nx"},
+		{"1\n2\n3\n4\n5\n6", "1\n3\n4\n7\n5\n6\n2"},
+		{"same\nsame\nsame", "same"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"|"+tt.b, func(t *testing.T) {
+			a, b := splitLines(tt.a), splitLines(tt.b)
+			var gotA, gotB []string
+			edits := 0
+			for _, line := range Lines(tt.a, tt.b) {
+				if line.Op != Insert {
+					gotA = append(gotA, line.Text)
+				}
+				if line.Op != Delete {
+					gotB = append(gotB, line.Text)
+				}
+				if line.Op != Equal {
+					edits++
+				}
+			}
+
+			if strings.Join(gotA, "\n") != strings.Join(a, "\n") || strings.Join(gotB, "\n") != strings.Join(b, "\n") {
+				t.Fatalf("script does not rebuild the inputs: got %q and %q", gotA, gotB)
+			}
+			if want := len(a) + len(b) - 2*lcs(a, b); edits != want {
+				t.Errorf("script makes %d edits, want %d", edits, want)
+			}
+		})
+	}
+}
+
+func lcs(a, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	return lengths[0][0]
+}
@@ -0,0 +1,175 @@
+package graph
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Node struct {
+	ID        uuid.UUID `json:"id"`
+	Title     string    `json:"title"`
+	Tags      []string  `json:"tags"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Edge connects two notes. Links counts the wiki links between them in
+// either direction and SharedTags the tags both carry; Weight adds them up.
+type Edge struct {
+	Source     uuid.UUID `json:"source"`
+	Target     uuid.UUID `json:"target"`
+	Weight     int       `json:"weight"`
+	Links      int       `json:"links"`
+	SharedTags int       `json:"sharedTags"`
+}
+
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+type pair [2]uuid.UUID
+
+func newPair(a, b uuid.UUID) pair {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	return pair{a, b}
+}
+
+// Builder collects nodes and connections and merges the connections between
+// the same two notes into one undirected edge.
+type Builder struct {
+	nodes []Node
+	index map[uuid.UUID]int
+	edges map[pair]*Edge
+}
+
+func NewBuilder() *Builder {
+	return &Builder{
+		index: make(map[uuid.UUID]int),
+		edges: make(map[pair]*Edge),
+	}
+}
+
+func (b *Builder) AddNode(node Node) {
+	if node.Tags == nil {
+		node.Tags = []string{}
+	}
+	b.index[node.ID] = len(b.nodes)
+	b.nodes = append(b.nodes, node)
+}
+
+// AddLinks records count links between two notes. Connections to notes
+// that were not added as nodes, or of a note with itself, are ignored.
+func (b *Builder) AddLinks(from, to uuid.UUID, count int) {
+	if edge := b.edge(from, to); edge != nil {
+		edge.Links += count
+	}
+}
+
+// AddSharedTags records count tags shared by two notes.
+func (b *Builder) AddSharedTags(from, to uuid.UUID, count int) {
+	if edge := b.edge(from, to); edge != nil {
+		edge.SharedTags += count
+	}
+}
+
+func (b *Builder) edge(from, to uuid.UUID) *Edge {
+	if from == to {
+		return nil
+	}
+	if _, ok := b.index[from]; !ok {
+		return nil
+	}
+	if _, ok := b.index[to]; !ok {
+		return nil
+	}
+
+	key := newPair(from, to)
+	edge, ok := b.edges[key]
+	if !ok {
+		edge = &Edge{Source: key[0], Target: key[1]}
+		b.edges[key] = edge
+	}
+	return edge
+}
+
+func (b *Builder) Graph() *Graph {
+	g := &Graph{
+		Nodes: append([]Node{}, b.nodes...),
+		Edges: make([]Edge, 0, len(b.edges)),
+	}
+	for _, edge := range b.edges {
+		edge.Weight = edge.Links + edge.SharedTags
+		g.Edges = append(g.Edges, *edge)
+	}
+
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].Weight != g.Edges[j].Weight {
+			return g.Edges[i].Weight > g.Edges[j].Weight
+		}
+		if c := bytes.Compare(g.Edges[i].Source[:], g.Edges[j].Source[:]); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(g.Edges[i].Target[:], g.Edges[j].Target[:]) < 0
+	})
+
+	return g
+}
+
+// Around keeps the nodes at most depth edges away from focus and the edges
+// between them. It reports false when focus is not in the graph.
+func (g *Graph) Around(focus uuid.UUID, depth int) (*Graph, bool) {
+	neighbours := make(map[uuid.UUID][]uuid.UUID)
+	for _, edge := range g.Edges {
+		neighbours[edge.Source] = append(neighbours[edge.Source], edge.Target)
+		neighbours[edge.Target] = append(neighbours[edge.Target], edge.Source)
+	}
+
+	found := false
+	for _, node := range g.Nodes {
+		if node.ID == focus {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, false
+	}
+
+	distance := map[uuid.UUID]int{focus: 0}
+	queue := []uuid.UUID{focus}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if distance[current] == depth {
+			continue
+		}
+		for _, next := range neighbours[current] {
+			if _, seen := distance[next]; !seen {
+				distance[next] = distance[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+
+	sub := &Graph{Nodes: []Node{}, Edges: []Edge{}}
+	for _, node := range g.Nodes {
+		if _, ok := distance[node.ID]; ok {
+			sub.Nodes = append(sub.Nodes, node)
+		}
+	}
+	for _, edge := range g.Edges {
+		_, hasSource := distance[edge.Source]
+		_, hasTarget := distance[edge.Target]
+		if hasSource && hasTarget {
+			sub.Edges = append(sub.Edges, edge)
+		}
+	}
+
+	return sub, true
+}
@@ -0,0 +1,68 @@
+package graph
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAround(t *testing.T) {
+	a, b, c, d, e := uuid.New(), uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	names := map[uuid.UUID]string{a: "a", b: "b", c: "c", d: "d", e: "e"}
+
+	// a - b - c - d form a chain and e stands alone.
+	builder := NewBuilder()
+	for _, id := range []uuid.UUID{a, b, c, d, e} {
+		builder.AddNode(Node{ID: id, Title: names[id]})
+	}
+	builder.AddLinks(a, b, 1)
+	builder.AddLinks(b, c, 1)
+	builder.AddSharedTags(c, d, 1)
+	g := builder.Graph()
+
+	tests := []struct {
+		name      string
+		focus     uuid.UUID
+		depth     int
+		wantNodes []string
+		wantEdges int
+	}{
+		{"depth zero keeps the focus", a, 0, []string{"a"}, 0},
+		{"one step", a, 1, []string{"a", "b"}, 1},
+		{"stops at the depth", a, 2, []string{"a", "b", "c"}, 2},
+		{"whole component", a, 5, []string{"a", "b", "c", "d"}, 3},
+		{"both directions", c, 1, []string{"b", "c", "d"}, 2},
+		{"isolated note", e, 3, []string{"e"}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub, ok := g.Around(tt.focus, tt.depth)
+			if !ok {
+				t.Fatal("Around() reported the focus missing")
+			}
+
+			nodes := []string{}
+			for _, node := range sub.Nodes {
+				nodes = append(nodes, node.Title)
+			}
+			if !reflect.DeepEqual(nodes, tt.wantNodes) {
+				t.Errorf("nodes = %v, want %v", nodes, tt.wantNodes)
+			}
+			if len(sub.Edges) != tt.wantEdges {
+				t.Errorf("got %d edges, want %d", len(sub.Edges), tt.wantEdges)
+			}
+			for _, edge := range sub.Edges {
+				if !slices.Contains(nodes, names[edge.Source]) || !slices.Contains(nodes, names[edge.Target]) {
+					t.Errorf("edge %s-%s leaves the subgraph", names[edge.Source], names[edge.Target])
+				}
+			}
+		})
+	}
+
+	if sub, ok := g.Around(uuid.New(), 1); ok || sub != nil {
+		t.Errorf("Around(missing) = %v, %v, want nil, false", sub, ok)
+	}
+}
@@ -0,0 +1,114 @@
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// maxLineOctets is the longest content line RFC 5545 allows before it has
+// to be folded, not counting the line break.
+const maxLineOctets = 75
+
+const dateTimeLayout = "20060102T150405Z"
+
+// Calendar is a published iCalendar object holding events.
+type Calendar struct {
+	ProdID string
+	Name   string
+	Events []Event
+}
+
+// Event is a VEVENT that starts and ends at Start. Alarm, when set, adds a
+// display alarm firing at that time.
+type Event struct {
+	UID         string
+	Stamp       time.Time
+	Start       time.Time
+	Summary     string
+	Description string
+	URL         string
+	Alarm       *time.Time
+}
+
+// Write encodes cal as an RFC 5545 stream, with CRLF line breaks and long
+// lines folded.
+func Write(w io.Writer, cal Calendar) error {
+	out := bufio.NewWriter(w)
+	line := func(name, value string) {
+		writeLine(out, name+":"+value)
+	}
+
+	line("BEGIN", "VCALENDAR")
+	line("VERSION", "2.0")
+	line("PRODID", cal.ProdID)
+	line("CALSCALE", "GREGORIAN")
+	line("METHOD", "PUBLISH")
+	if cal.Name != "" {
+		line("X-WR-CALNAME", escapeText(cal.Name))
+	}
+
+	for _, event := range cal.Events {
+		line("BEGIN", "VEVENT")
+		line("UID", event.UID)
+		line("DTSTAMP", formatTime(event.Stamp))
+		line("DTSTART", formatTime(event.Start))
+		line("SUMMARY", escapeText(event.Summary))
+		if event.Description != "" {
+			line("DESCRIPTION", escapeText(event.Description))
+		}
+		if event.URL != "" {
+			line("URL;VALUE=URI", event.URL)
+		}
+		if event.Alarm != nil {
+			line("BEGIN", "VALARM")
+			line("ACTION", "DISPLAY")
+			line("DESCRIPTION", escapeText(event.Summary))
+			line("TRIGGER;VALUE=DATE-TIME", formatTime(*event.Alarm))
+			line("END", "VALARM")
+		}
+		line("END", "VEVENT")
+	}
+
+	line("END", "VCALENDAR")
+	return out.Flush()
+}
+
+func formatTime(t time.Time) string {
+	return t.UTC().Format(dateTimeLayout)
+}
+
+// escapeText escapes a TEXT value, which cannot hold raw line breaks,
+// commas or semicolons.
+func escapeText(text string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(text)
+}
+
+// writeLine writes a content line, folding it onto continuation lines that
+// start with a space so that none exceeds maxLineOctets. Lines are only
+// split between characters, never inside a UTF-8 sequence.
+func writeLine(out *bufio.Writer, line string) {
+	limit := maxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		out.WriteString(line[:cut])
+		out.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation line counts towards it.
+		limit = maxLineOctets - 1
+	}
+	out.WriteString(line)
+	out.WriteString("\r\n")
+}
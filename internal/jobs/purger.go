@@ -0,0 +1,56 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+)
+
+// TrashPurger permanently removes notes that have been in the trash for
+// longer than the retention period.
+type TrashPurger struct {
+	notes     store.NoteStorage
+	logger    *zap.SugaredLogger
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewTrashPurger(notes store.NoteStorage, logger *zap.SugaredLogger, retention, interval time.Duration) *TrashPurger {
+	return &TrashPurger{
+		notes:     notes,
+		logger:    logger,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// Run purges once right away and then on every interval until ctx is done.
+func (p *TrashPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		p.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *TrashPurger) purge(ctx context.Context) {
+	cutoff := time.Now().Add(-p.retention)
+	purged, err := p.notes.PurgeDeletedBefore(ctx, cutoff)
+	if err != nil {
+		p.logger.Errorw("failed to purge trashed notes", "error", err)
+		return
+	}
+	if purged > 0 {
+		p.logger.Infow("purged trashed notes", "count", purged, "cutoff", cutoff)
+	}
+}
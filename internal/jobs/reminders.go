@@ -0,0 +1,112 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+)
+
+// Notifier delivers a reminder to the owner of the note. A reminder Notify
+// fails on is tried again later.
+type Notifier interface {
+	Notify(ctx context.Context, reminder models.Reminder) error
+}
+
+// LogNotifier delivers reminders by writing them to the log, which is all
+// that is needed until a real channel such as email is wired in.
+type LogNotifier struct {
+	logger *zap.SugaredLogger
+}
+
+func NewLogNotifier(logger *zap.SugaredLogger) *LogNotifier {
+	return &LogNotifier{
+		logger: logger,
+	}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, reminder models.Reminder) error {
+	n.logger.Infow("note reminder",
+		"note", reminder.NoteID,
+		"user", reminder.UserID,
+		"title", reminder.Title,
+		"remindAt", reminder.RemindAt,
+		"dueAt", reminder.DueAt,
+	)
+	return nil
+}
+
+// ReminderScheduler delivers note reminders once they are due. Several
+// schedulers can run against the same database, each reminder is still
+// delivered by only one of them.
+type ReminderScheduler struct {
+	notes     store.NoteStorage
+	notifier  Notifier
+	logger    *zap.SugaredLogger
+	interval  time.Duration
+	batchSize int
+}
+
+func NewReminderScheduler(notes store.NoteStorage, notifier Notifier, logger *zap.SugaredLogger, interval time.Duration, batchSize int) *ReminderScheduler {
+	return &ReminderScheduler{
+		notes:     notes,
+		notifier:  notifier,
+		logger:    logger,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run delivers the due reminders once right away and then on every
+// interval until ctx is done.
+func (s *ReminderScheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		s.deliver(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deliver works through the due reminders a batch at a time, stopping at
+// the first batch that is not full. A reminder the notifier fails on is
+// released and tried again on the next interval. A crash between claiming
+// and notifying drops the reminder rather than sending it twice.
+func (s *ReminderScheduler) deliver(ctx context.Context) {
+	for ctx.Err() == nil {
+		reminders, err := s.notes.ClaimReminders(ctx, time.Now(), s.batchSize)
+		if err != nil {
+			s.logger.Errorw("failed to claim reminders", "error", err)
+			return
+		}
+
+		delivered := 0
+		for _, reminder := range reminders {
+			if err := s.notifier.Notify(ctx, reminder); err != nil {
+				s.logger.Errorw("failed to deliver reminder", "note", reminder.NoteID, "error", err)
+				// The claim is undone even when ctx is done, so the
+				// reminder is not lost on shutdown.
+				if err := s.notes.ReleaseReminder(context.WithoutCancel(ctx), reminder.NoteID); err != nil {
+					s.logger.Errorw("failed to release reminder", "note", reminder.NoteID, "error", err)
+				}
+				continue
+			}
+			delivered++
+		}
+		if delivered > 0 {
+			s.logger.Infow("delivered reminders", "count", delivered)
+		}
+		if len(reminders) < s.batchSize || delivered < len(reminders) {
+			return
+		}
+	}
+}
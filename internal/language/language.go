@@ -0,0 +1,77 @@
+package language
+
+import (
+	"slices"
+	"strings"
+	"unicode"
+)
+
+var Supported = []string{"es", "en", "it"}
+
+var stopwords = map[string][]string{
+	"es": {
+		"el", "la", "los", "las", "un", "una", "unos", "unas", "de", "del", "que", "y",
+		"en", "por", "para", "con", "no", "es", "se", "lo", "su", "al", "como", "pero",
+		"más", "muy", "este", "esta", "son", "está", "también", "hay", "cuando", "porque",
+	},
+	"en": {
+		"the", "a", "an", "of", "and", "to", "in", "is", "it", "that", "for", "on",
+		"with", "as", "was", "are", "be", "this", "have", "from", "or", "by", "not",
+		"but", "what", "all", "were", "when", "there", "can", "will", "my", "your",
+	},
+	"it": {
+		"il", "lo", "la", "gli", "le", "un", "uno", "una", "di", "del", "della", "che",
+		"e", "è", "per", "con", "non", "sono", "si", "da", "nel", "nella", "ma", "come",
+		"anche", "più", "questo", "questa", "perché", "quando", "alla", "dei", "delle",
+	},
+}
+
+var hints = map[string][]rune{
+	"es": {'ñ', '¿', '¡'},
+	"it": {'ì', 'ò', 'ù'},
+}
+
+func IsSupported(code string) bool {
+	return slices.Contains(Supported, code)
+}
+
+// Detect guesses the language of text by counting stopwords and a few
+// language-specific characters. When the text gives no clear signal the
+// fallback is returned.
+func Detect(text, fallback string) string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	scores := make(map[string]int, len(Supported))
+	for _, word := range words {
+		for lang, list := range stopwords {
+			if slices.Contains(list, word) {
+				scores[lang]++
+			}
+		}
+	}
+
+	for lang, runes := range hints {
+		for _, r := range runes {
+			if strings.ContainsRune(text, r) {
+				scores[lang] += 2
+			}
+		}
+	}
+
+	best, bestScore, tie := fallback, 0, false
+	for _, lang := range Supported {
+		switch score := scores[lang]; {
+		case score > bestScore:
+			best, bestScore, tie = lang, score, false
+		case score == bestScore && score > 0:
+			tie = true
+		}
+	}
+
+	if bestScore == 0 || tie {
+		return fallback
+	}
+	return best
+}
@@ -0,0 +1,36 @@
+package language
+
+import "testing"
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		text     string
+		fallback string
+		want     string
+	}{
+		{"", "en", "en"},
+		{"12345 !!", "es", "es"},
+		{"The cat and the dog", "es", "en"},
+		{"EL PERRO Y LA CASA", "en", "es"},
+		{"il gatto e la casa", "en", "it"},
+		{"año", "en", "es"},
+		{"¿Qué?", "it", "es"},
+		{"perché", "en", "it"},
+		// No stopword of any supported language.
+		{"der Hund schläft", "en", "en"},
+		// la is a stopword in both Spanish and Italian.
+		{"la", "en", "en"},
+		{"the el", "it", "it"},
+		{"the el il", "", ""},
+		// A hint outweighs a single stopword of another language.
+		{"the niño", "it", "es"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			if got := Detect(tt.text, tt.fallback); got != tt.want {
+				t.Errorf("Detect(%q, %q) = %q, want %q", tt.text, tt.fallback, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package markdown
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/manuelmtzv/mangocatnotes-api/internal/kvstore"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+)
+
+// cacheTTL bounds how long the HTML of a note version is kept. Edits bump
+// the version, so stale entries are never read and simply expire.
+const cacheTTL = 24 * time.Hour
+
+// Renderer turns note content written in CommonMark with the GFM extensions
+// into sanitized HTML.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+	cache  kvstore.KVStorage
+}
+
+func NewRenderer(cache kvstore.KVStorage) *Renderer {
+	// Raw HTML in the source is dropped by goldmark and anything else that
+	// gets through has to pass the allow-list.
+	policy := bluemonday.UGCPolicy()
+	policy.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[\w+-]+$`)).OnElements("code")
+	policy.AllowAttrs("type").Matching(regexp.MustCompile(`^checkbox$`)).OnElements("input")
+	policy.AllowAttrs("checked", "disabled").OnElements("input")
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+
+	return &Renderer{
+		md:     goldmark.New(goldmark.WithExtensions(extension.GFM)),
+		policy: policy,
+		cache:  cache,
+	}
+}
+
+// Render converts source to sanitized HTML.
+func (r *Renderer) Render(source string) (string, error) {
+	var buf bytes.Buffer
+	if err := r.md.Convert([]byte(source), &buf); err != nil {
+		return "", err
+	}
+	return r.policy.Sanitize(buf.String()), nil
+}
+
+// Note renders the content of the note, reusing the HTML cached for its
+// current version when there is one.
+func (r *Renderer) Note(ctx context.Context, note *models.Note) (string, error) {
+	key := fmt.Sprintf("note-html:%s:%d", note.ID, note.Version)
+	if html, err := r.cache.Get(ctx, key); err == nil {
+		return html, nil
+	}
+
+	html, err := r.Render(note.Content)
+	if err != nil {
+		return "", err
+	}
+
+	// A cache that cannot be written only costs a render next time.
+	_ = r.cache.Set(ctx, key, html, cacheTTL)
+	return html, nil
+}
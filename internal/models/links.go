@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NoteLink is a [[target]] reference in the content of a note. NoteID and
+// Title are nil while no note matches the target.
+type NoteLink struct {
+	SourceID uuid.UUID  `db:"source_id" json:"sourceId"`
+	Target   string     `db:"target" json:"target"`
+	NoteID   *uuid.UUID `db:"note_id" json:"noteId"`
+	Title    *string    `db:"title" json:"title"`
+	Dangling bool       `db:"-" json:"dangling"`
+}
+
+// Backlink is a note whose content links to another note.
+type Backlink struct {
+	NoteID    uuid.UUID `db:"note_id" json:"noteId"`
+	Title     string    `db:"title" json:"title"`
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NoteTemplate is the starting point for new notes. Title and Content may
+// hold {{date}}, {{time}} and {{title}} placeholders, and Tags are given to
+// every note created from it.
+type NoteTemplate struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	UserID    uuid.UUID `db:"user_id" json:"userId"`
+	Name      string    `db:"name" json:"name"`
+	Title     string    `db:"title" json:"title"`
+	Content   string    `db:"content" json:"content"`
+	Language  string    `db:"language" json:"language"`
+	Tags      []string  `db:"tags" json:"tags"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+}
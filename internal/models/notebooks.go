@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notebook groups notes. Notebooks nest through ParentID, and those without
+// a parent sit at the root.
+type Notebook struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	UserID    uuid.UUID  `db:"user_id" json:"userId"`
+	ParentID  *uuid.UUID `db:"parent_id" json:"parentId"`
+	Name      string     `db:"name" json:"name"`
+	NoteCount int        `db:"-" json:"noteCount"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updatedAt"`
+}
@@ -7,15 +7,31 @@ import (
 )
 
 type Note struct {
-	ID        uuid.UUID `db:"id" json:"id"`
-	UserID    uuid.UUID `db:"user_id" json:"userId"`
-	Title     string    `db:"title" json:"title"`
-	Content   string    `db:"content" json:"content"`
-	Archived  bool      `db:"archived" json:"archived"`
-	CreatedAt time.Time `db:"created_at" json:"createdAt"`
-	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+	ID         uuid.UUID  `db:"id" json:"id"`
+	UserID     uuid.UUID  `db:"user_id" json:"userId"`
+	NotebookID *uuid.UUID `db:"notebook_id" json:"notebookId"`
+	Title      string     `db:"title" json:"title"`
+	Content    string     `db:"content" json:"content"`
+	Language   string     `db:"language" json:"language"`
+	Archived   bool       `db:"archived" json:"archived"`
+	Pinned     bool       `db:"pinned" json:"pinned"`
+	Position   int        `db:"position" json:"position"`
+	Version    int        `db:"version" json:"version"`
+	CreatedAt  time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt  time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt  *time.Time `db:"deleted_at" json:"deletedAt,omitempty"`
+
+	// DueAt and RemindAt are instants in UTC; RemindedAt records when the
+	// reminder was delivered.
+	DueAt      *time.Time `db:"due_at" json:"dueAt"`
+	RemindAt   *time.Time `db:"remind_at" json:"remindAt"`
+	RemindedAt *time.Time `db:"reminded_at" json:"remindedAt,omitempty"`
 
 	Tags []Tag `db:"-" json:"tags"`
+
+	Rank             float32 `db:"rank" json:"rank,omitempty"`
+	TitleHighlight   string  `db:"-" json:"titleHighlight,omitempty"`
+	ContentHighlight string  `db:"-" json:"contentHighlight,omitempty"`
 }
 
 type PaginatedNotesResponse struct {
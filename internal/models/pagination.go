@@ -1,8 +1,14 @@
 package models
 
+// PaginationMetadata describes one page of a listing. Offset pagination
+// fills Page, Count and TotalPages. Cursor pagination skips counting and
+// fills NextCursor instead, which is empty on the last page.
 type PaginationMetadata struct {
-	Page       int64 `json:"page"`
-	Limit      int64 `json:"limit"`
-	Count      int64 `json:"count"`
-	TotalPages int64 `json:"totalPages"`
+	Page       int64  `json:"page,omitempty"`
+	Limit      int64  `json:"limit"`
+	Count      int64  `json:"count,omitempty"`
+	TotalPages int64  `json:"totalPages,omitempty"`
+	Sort       string `json:"sort,omitempty"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	HasMore    bool   `json:"hasMore"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reminder is a due note reminder together with what a notifier needs to
+// reach its owner.
+type Reminder struct {
+	NoteID   uuid.UUID  `json:"noteId"`
+	UserID   uuid.UUID  `json:"userId"`
+	Email    string     `json:"email"`
+	Username string     `json:"username"`
+	Name     string     `json:"name,omitempty"`
+	Timezone string     `json:"timezone"`
+	Title    string     `json:"title"`
+	DueAt    *time.Time `json:"dueAt"`
+	RemindAt time.Time  `json:"remindAt"`
+}
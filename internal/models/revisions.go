@@ -0,0 +1,17 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NoteRevision is a snapshot of a note taken right before it was updated.
+type NoteRevision struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	NoteID    uuid.UUID `db:"note_id" json:"noteId"`
+	Revision  int       `db:"revision" json:"revision"`
+	Title     string    `db:"title" json:"title"`
+	Content   string    `db:"content" json:"content"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+}
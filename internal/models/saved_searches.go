@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SavedSearch is a named combination of dashboard filters. Search holds the
+// query text as typed, so it is parsed again every time the search runs.
+type SavedSearch struct {
+	ID        uuid.UUID `db:"id" json:"id"`
+	UserID    uuid.UUID `db:"user_id" json:"userId"`
+	Name      string    `db:"name" json:"name"`
+	Search    string    `db:"search" json:"search"`
+	Tags      []string  `db:"tags" json:"tags"`
+	TagMatch  string    `db:"tag_match" json:"match"`
+	Sort      string    `db:"sort" json:"sort"`
+	CreatedAt time.Time `db:"created_at" json:"createdAt"`
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+
+	NoteCount *int64 `db:"-" json:"noteCount,omitempty"`
+}
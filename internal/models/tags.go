@@ -6,16 +6,32 @@ import (
 	"github.com/google/uuid"
 )
 
+// Tag labels notes. Tags nest through /-separated path names such as
+// work/clients/acme, and ParentID points at the tag one level up.
 type Tag struct {
-	ID        uuid.UUID `db:"id" json:"id"`
-	UserID    uuid.UUID `db:"user_id" json:"userId"`
-	Name      string    `db:"name" json:"name"`
-	Color     string    `db:"color" json:"color"`
-	CreatedAt time.Time `db:"created_at" json:"createdAt"`
-	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+	ID        uuid.UUID  `db:"id" json:"id"`
+	UserID    uuid.UUID  `db:"user_id" json:"userId"`
+	ParentID  *uuid.UUID `db:"parent_id" json:"parentId"`
+	Name      string     `db:"name" json:"name"`
+	Color     string     `db:"color" json:"color"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt time.Time  `db:"updated_at" json:"updatedAt"`
+
+	// NoteCount and LastUsedAt are only filled in when usage is asked for.
+	NoteCount  *int       `db:"-" json:"noteCount,omitempty"`
+	LastUsedAt *time.Time `db:"-" json:"lastUsedAt,omitempty"`
 }
 
 type PaginatedTagsResponse struct {
 	Data []Tag              `json:"data"`
 	Meta PaginationMetadata `json:"meta"`
 }
+
+// TagNode is a tag in the tag tree. The tag's NoteCount counts the notes
+// carrying the tag itself and TotalCount those carrying it or any of its
+// descendants.
+type TagNode struct {
+	Tag
+	TotalCount int       `json:"totalCount"`
+	Children   []TagNode `json:"children"`
+}
@@ -7,11 +7,15 @@ import (
 )
 
 type User struct {
-	ID        uuid.UUID `db:"id" json:"id"`
-	Email     string    `db:"email" json:"email"`
-	Username  string    `db:"username" json:"username"`
-	Hash      string    `db:"hash" json:"-"`
-	Name      string    `db:"name" json:"name,omitempty"`
-	CreatedAt time.Time `db:"created_at" json:"createdAt"`
-	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+	ID                uuid.UUID  `db:"id" json:"id"`
+	Email             string     `db:"email" json:"email"`
+	Username          string     `db:"username" json:"username"`
+	Hash              string     `db:"hash" json:"-"`
+	Name              string     `db:"name" json:"name,omitempty"`
+	RevisionRetention int        `db:"revision_retention" json:"revisionRetention"`
+	DailyTemplateID   *uuid.UUID `db:"daily_template_id" json:"dailyTemplateId"`
+	Timezone          string     `db:"timezone" json:"timezone"`
+	CalendarTokenHash *string    `db:"calendar_token_hash" json:"-"`
+	CreatedAt         time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt         time.Time  `db:"updated_at" json:"updatedAt"`
 }
@@ -0,0 +1,33 @@
+package notetemplate
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// placeholderPattern matches {{name}}, allowing spaces inside the braces.
+var placeholderPattern = regexp.MustCompile(`\{\{\s*([A-Za-z]+)\s*\}\}`)
+
+// Vars are the values a template can refer to.
+type Vars struct {
+	Now   time.Time
+	Title string
+}
+
+// Render replaces {{date}}, {{time}} and {{title}} in text. Names are not
+// case sensitive, and unknown placeholders are left as they are.
+func Render(text string, vars Vars) string {
+	return placeholderPattern.ReplaceAllStringFunc(text, func(match string) string {
+		name := placeholderPattern.FindStringSubmatch(match)[1]
+		switch strings.ToLower(name) {
+		case "date":
+			return vars.Now.Format("2006-01-02")
+		case "time":
+			return vars.Now.Format("15:04")
+		case "title":
+			return vars.Title
+		}
+		return match
+	})
+}
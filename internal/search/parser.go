@@ -0,0 +1,202 @@
+package search
+
+import (
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const dateLayout = "2006-01-02"
+
+var Flags = []string{"archived", "pinned"}
+
+var dateFields = map[string]string{
+	"created": "",
+	"updated": "",
+	"before":  "<",
+	"after":   ">",
+}
+
+type token struct {
+	negated bool
+	key     string
+	value   string
+	quoted  bool
+	pos     int
+}
+
+// Parse turns a query such as
+//
+//	tag:work -tag:old is:archived created:>2025-01-01 "exact phrase" OR draft
+//
+// into a Query. Terms are joined with AND; OR binds the terms on either side
+// of it into a single clause.
+func Parse(input string) (*Query, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	query := &Query{}
+	joinNext := false
+	for i, tok := range tokens {
+		if tok.isOr() {
+			if i == 0 || i == len(tokens)-1 || joinNext {
+				return nil, &Error{Key: "search.error.misplaced_or", Param: "OR", Pos: tok.pos}
+			}
+			joinNext = true
+			continue
+		}
+
+		term, err := tok.term()
+		if err != nil {
+			return nil, err
+		}
+
+		if joinNext {
+			last := len(query.Clauses) - 1
+			query.Clauses[last] = append(query.Clauses[last], term)
+			joinNext = false
+		} else {
+			query.Clauses = append(query.Clauses, Clause{term})
+		}
+	}
+
+	return query, nil
+}
+
+func tokenize(input string) ([]token, error) {
+	runes := []rune(input)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		tok := token{pos: i}
+		if runes[i] == '-' {
+			tok.negated = true
+			i++
+			if i == len(runes) || unicode.IsSpace(runes[i]) {
+				return nil, &Error{Key: "search.error.empty_term", Param: "-", Pos: tok.pos}
+			}
+		}
+
+		start := i
+		for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '"' {
+			if runes[i] == ':' && tok.key == "" {
+				tok.key = strings.ToLower(string(runes[start:i]))
+				start = i + 1
+			}
+			i++
+		}
+		tok.value = string(runes[start:i])
+
+		if i < len(runes) && runes[i] == '"' {
+			if tok.value != "" {
+				return nil, &Error{Key: "search.error.unexpected_quote", Param: string(runes[tok.pos : i+1]), Pos: i}
+			}
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, &Error{Key: "search.error.unterminated_quote", Param: string(runes[i:]), Pos: i}
+			}
+			tok.value = string(runes[i+1 : end])
+			tok.quoted = true
+			i = end + 1
+		}
+
+		tokens = append(tokens, tok)
+	}
+
+	return tokens, nil
+}
+
+func (t token) isOr() bool {
+	return !t.negated && !t.quoted && t.key == "" && t.value == "OR"
+}
+
+func (t token) raw() string {
+	raw := t.value
+	if t.quoted {
+		raw = `"` + raw + `"`
+	}
+	if t.key != "" {
+		raw = t.key + ":" + raw
+	}
+	if t.negated {
+		raw = "-" + raw
+	}
+	return raw
+}
+
+func (t token) term() (Term, error) {
+	term := Term{Negated: t.negated, Value: t.value}
+
+	switch t.key {
+	case "":
+		term.Kind = KindText
+		if t.quoted {
+			term.Kind = KindPhrase
+		}
+	case "tag":
+		term.Kind = KindTag
+	case "is":
+		term.Kind = KindFlag
+		term.Value = strings.ToLower(t.value)
+		if !slices.Contains(Flags, term.Value) {
+			return Term{}, &Error{Key: "search.error.unknown_flag", Param: t.raw(), Pos: t.pos}
+		}
+	default:
+		op, isDate := dateFields[t.key]
+		if !isDate {
+			// Not a filter we know about (a URL, a time of day...), so
+			// search for the text as typed.
+			term.Kind = KindText
+			term.Value = t.key + ":" + t.value
+			if t.quoted {
+				term.Kind = KindPhrase
+			}
+			return term, nil
+		}
+		return t.dateTerm(op)
+	}
+
+	if strings.TrimSpace(term.Value) == "" {
+		return Term{}, &Error{Key: "search.error.empty_term", Param: t.raw(), Pos: t.pos}
+	}
+	return term, nil
+}
+
+func (t token) dateTerm(op string) (Term, error) {
+	term := Term{Kind: KindDate, Negated: t.negated, Field: t.key, Op: op}
+	value := t.value
+
+	if op == "" {
+		for _, candidate := range []string{">=", "<=", ">", "<", "="} {
+			if strings.HasPrefix(value, candidate) {
+				term.Op = candidate
+				value = strings.TrimPrefix(value, candidate)
+				break
+			}
+		}
+		if term.Op == "" {
+			term.Op = "="
+		}
+	} else {
+		term.Field = "created"
+	}
+
+	date, err := time.Parse(dateLayout, value)
+	if err != nil {
+		return Term{}, &Error{Key: "search.error.invalid_date", Param: t.raw(), Pos: t.pos}
+	}
+	term.Date = date
+	term.Value = value
+	return term, nil
+}
@@ -0,0 +1,151 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func date(value string) time.Time {
+	day, err := time.Parse(dateLayout, value)
+	if err != nil {
+		panic(err)
+	}
+	return day
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []Clause
+	}{
+		{"", nil},
+		{"   ", nil},
+		{"draft", []Clause{{{Kind: KindText, Value: "draft"}}}},
+		{`"exact phrase"`, []Clause{{{Kind: KindPhrase, Value: "exact phrase"}}}},
+		{"a b", []Clause{
+			{{Kind: KindText, Value: "a"}},
+			{{Kind: KindText, Value: "b"}},
+		}},
+		// OR binds the terms next to it more tightly than the implicit AND.
+		{"a OR b c", []Clause{
+			{{Kind: KindText, Value: "a"}, {Kind: KindText, Value: "b"}},
+			{{Kind: KindText, Value: "c"}},
+		}},
+		{"a b OR c", []Clause{
+			{{Kind: KindText, Value: "a"}},
+			{{Kind: KindText, Value: "b"}, {Kind: KindText, Value: "c"}},
+		}},
+		{"a OR b OR c", []Clause{
+			{{Kind: KindText, Value: "a"}, {Kind: KindText, Value: "b"}, {Kind: KindText, Value: "c"}},
+		}},
+		// Only an uppercase, bare OR is an operator.
+		{"a or b", []Clause{
+			{{Kind: KindText, Value: "a"}},
+			{{Kind: KindText, Value: "or"}},
+			{{Kind: KindText, Value: "b"}},
+		}},
+		{`a "OR" b`, []Clause{
+			{{Kind: KindText, Value: "a"}},
+			{{Kind: KindPhrase, Value: "OR"}},
+			{{Kind: KindText, Value: "b"}},
+		}},
+		{"-draft", []Clause{{{Kind: KindText, Value: "draft", Negated: true}}}},
+		{`-"old idea"`, []Clause{{{Kind: KindPhrase, Value: "old idea", Negated: true}}}},
+		{"-tag:old", []Clause{{{Kind: KindTag, Value: "old", Negated: true}}}},
+		{"TAG:Work/Client", []Clause{{{Kind: KindTag, Value: "Work/Client"}}}},
+		{`tag:"big project"`, []Clause{{{Kind: KindTag, Value: "big project"}}}},
+		{"is:Archived -is:pinned", []Clause{
+			{{Kind: KindFlag, Value: "archived"}},
+			{{Kind: KindFlag, Value: "pinned", Negated: true}},
+		}},
+		{"created:>2025-01-01", []Clause{{{Kind: KindDate, Field: "created", Op: ">", Value: "2025-01-01", Date: date("2025-01-01")}}}},
+		{"updated:<=2025-01-31", []Clause{{{Kind: KindDate, Field: "updated", Op: "<=", Value: "2025-01-31", Date: date("2025-01-31")}}}},
+		{"updated:2025-01-01", []Clause{{{Kind: KindDate, Field: "updated", Op: "=", Value: "2025-01-01", Date: date("2025-01-01")}}}},
+		{"before:2025-03-01", []Clause{{{Kind: KindDate, Field: "created", Op: "<", Value: "2025-03-01", Date: date("2025-03-01")}}}},
+		{"-after:2025-03-01", []Clause{{{Kind: KindDate, Field: "created", Op: ">", Value: "2025-03-01", Date: date("2025-03-01"), Negated: true}}}},
+		// Unknown keys are searched for as typed.
+		{"https://example.com", []Clause{{{Kind: KindText, Value: "https://example.com"}}}},
+		{"at 10:30", []Clause{
+			{{Kind: KindText, Value: "at"}},
+			{{Kind: KindText, Value: "10:30"}},
+		}},
+		{`note:"to self"`, []Clause{{{Kind: KindPhrase, Value: "note:to self"}}}},
+		{"tag:work OR is:pinned", []Clause{
+			{{Kind: KindTag, Value: "work"}, {Kind: KindFlag, Value: "pinned"}},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := Parse(tt.input)
+			if err != nil {
+				t.Fatalf("Parse(%q) returned error: %v", tt.input, err)
+			}
+			if !reflect.DeepEqual(query.Clauses, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.input, query.Clauses, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Error
+	}{
+		{"-", Error{Key: "search.error.empty_term", Param: "-", Pos: 0}},
+		{"a - b", Error{Key: "search.error.empty_term", Param: "-", Pos: 2}},
+		{"tag:", Error{Key: "search.error.empty_term", Param: "tag:", Pos: 0}},
+		{`tag:"  "`, Error{Key: "search.error.empty_term", Param: `tag:"  "`, Pos: 0}},
+		{"OR a", Error{Key: "search.error.misplaced_or", Param: "OR", Pos: 0}},
+		{"a OR", Error{Key: "search.error.misplaced_or", Param: "OR", Pos: 2}},
+		{"a OR OR b", Error{Key: "search.error.misplaced_or", Param: "OR", Pos: 5}},
+		{`a "open`, Error{Key: "search.error.unterminated_quote", Param: `"open`, Pos: 2}},
+		{`ab"c"`, Error{Key: "search.error.unexpected_quote", Param: `ab"`, Pos: 2}},
+		{"is:deleted", Error{Key: "search.error.unknown_flag", Param: "is:deleted", Pos: 0}},
+		{"-is:", Error{Key: "search.error.unknown_flag", Param: "-is:", Pos: 0}},
+		{"created:yesterday", Error{Key: "search.error.invalid_date", Param: "created:yesterday", Pos: 0}},
+		{"x created:>2025-13-01", Error{Key: "search.error.invalid_date", Param: "created:>2025-13-01", Pos: 2}},
+		{"before:>2025-01-01", Error{Key: "search.error.invalid_date", Param: "before:>2025-01-01", Pos: 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			_, err := Parse(tt.input)
+			var searchErr *Error
+			if !errors.As(err, &searchErr) {
+				t.Fatalf("Parse(%q) error = %v, want %+v", tt.input, err, tt.want)
+			}
+			if *searchErr != tt.want {
+				t.Errorf("Parse(%q) error = %+v, want %+v", tt.input, *searchErr, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryHelpers(t *testing.T) {
+	query, err := Parse(`budget OR "cash flow" -draft tag:work -is:archived`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := query.TextQuery(), `budget or "cash flow"`; got != want {
+		t.Errorf("TextQuery() = %q, want %q", got, want)
+	}
+	if !query.HasFlag("archived") {
+		t.Error("HasFlag(archived) = false, want true")
+	}
+	if query.HasFlag("pinned") {
+		t.Error("HasFlag(pinned) = true, want false")
+	}
+	if query.IsEmpty() {
+		t.Error("IsEmpty() = true, want false")
+	}
+
+	var nilQuery *Query
+	if !nilQuery.IsEmpty() || nilQuery.HasFlag("archived") || nilQuery.TextQuery() != "" {
+		t.Error("a nil query should be empty")
+	}
+}
@@ -0,0 +1,95 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type Kind int
+
+const (
+	KindText Kind = iota
+	KindPhrase
+	KindTag
+	KindFlag
+	KindDate
+)
+
+// Term is a single search criterion such as a word, a "quoted phrase",
+// tag:name, is:flag or created:>date.
+type Term struct {
+	Kind    Kind
+	Negated bool
+	Value   string
+	Field   string
+	Op      string
+	Date    time.Time
+}
+
+// Clause holds terms joined with OR. A query matches when every clause has
+// at least one matching term.
+type Clause []Term
+
+type Query struct {
+	Clauses []Clause
+}
+
+// Error is a parse or compile error. Key is the translation id of the
+// message and Param the offending fragment of the query.
+type Error struct {
+	Key   string
+	Param string
+	Pos   int
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%s) at position %d", e.Key, e.Param, e.Pos)
+}
+
+func (q *Query) IsEmpty() bool {
+	return q == nil || len(q.Clauses) == 0
+}
+
+// HasFlag reports whether the query filters on is:flag, negated or not.
+func (q *Query) HasFlag(flag string) bool {
+	if q == nil {
+		return false
+	}
+	for _, clause := range q.Clauses {
+		for _, term := range clause {
+			if term.Kind == KindFlag && term.Value == flag {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// TextQuery returns the positive words and phrases in websearch_to_tsquery
+// syntax, for ranking and highlighting matches.
+func (q *Query) TextQuery() string {
+	if q == nil {
+		return ""
+	}
+
+	var parts []string
+	for _, clause := range q.Clauses {
+		var alternatives []string
+		for _, term := range clause {
+			if term.Negated {
+				continue
+			}
+			switch term.Kind {
+			case KindText:
+				alternatives = append(alternatives, term.Value)
+			case KindPhrase:
+				alternatives = append(alternatives, `"`+term.Value+`"`)
+			}
+		}
+		if len(alternatives) > 0 {
+			parts = append(parts, strings.Join(alternatives, " or "))
+		}
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,104 @@
+package search
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schema tells the compiler how each kind of term maps onto SQL. Text, Tag
+// and the entries of Flags and Dates receive column expressions or
+// placeholders and return boolean expressions.
+type Schema struct {
+	Text  func(arg string) string
+	Tag   func(arg string) string
+	Flags map[string]string
+	Dates map[string]string
+}
+
+// SQL compiles the query into a boolean expression whose placeholders start
+// after argOffset, along with the matching arguments.
+func (q *Query) SQL(schema Schema, argOffset int) (string, []any, error) {
+	if q.IsEmpty() {
+		return "TRUE", nil, nil
+	}
+
+	c := &compiler{schema: schema, offset: argOffset}
+
+	clauses := make([]string, 0, len(q.Clauses))
+	for _, clause := range q.Clauses {
+		alternatives := make([]string, 0, len(clause))
+		for _, term := range clause {
+			expr, err := c.term(term)
+			if err != nil {
+				return "", nil, err
+			}
+			alternatives = append(alternatives, expr)
+		}
+		clauses = append(clauses, "("+strings.Join(alternatives, " OR ")+")")
+	}
+
+	return strings.Join(clauses, " AND "), c.args, nil
+}
+
+type compiler struct {
+	schema Schema
+	offset int
+	args   []any
+}
+
+func (c *compiler) arg(value any) string {
+	c.args = append(c.args, value)
+	return fmt.Sprintf("$%d", c.offset+len(c.args))
+}
+
+func (c *compiler) term(term Term) (string, error) {
+	var expr string
+
+	switch term.Kind {
+	case KindText:
+		expr = c.schema.Text(c.arg(term.Value))
+	case KindPhrase:
+		expr = c.schema.Text(c.arg(`"` + term.Value + `"`))
+	case KindTag:
+		expr = c.schema.Tag(c.arg(term.Value))
+	case KindFlag:
+		column, ok := c.schema.Flags[term.Value]
+		if !ok {
+			return "", &Error{Key: "search.error.unsupported_filter", Param: "is:" + term.Value}
+		}
+		expr = column
+	case KindDate:
+		column, ok := c.schema.Dates[term.Field]
+		if !ok {
+			return "", &Error{Key: "search.error.unsupported_filter", Param: term.Field + ":" + term.Value}
+		}
+		expr = c.dateRange(column, term.Op, term.Date)
+	default:
+		return "", fmt.Errorf("unknown search term kind %d", term.Kind)
+	}
+
+	if term.Negated {
+		return "NOT (" + expr + ")", nil
+	}
+	return expr, nil
+}
+
+// dateRange compares at day granularity, so created:>2025-01-01 starts at
+// the following midnight and created:2025-01-01 covers the whole day.
+func (c *compiler) dateRange(column, op string, day time.Time) string {
+	next := day.AddDate(0, 0, 1)
+
+	switch op {
+	case ">":
+		return fmt.Sprintf("%s >= %s", column, c.arg(next))
+	case ">=":
+		return fmt.Sprintf("%s >= %s", column, c.arg(day))
+	case "<":
+		return fmt.Sprintf("%s < %s", column, c.arg(day))
+	case "<=":
+		return fmt.Sprintf("%s < %s", column, c.arg(next))
+	default:
+		return fmt.Sprintf("(%s >= %s AND %s < %s)", column, c.arg(day), column, c.arg(next))
+	}
+}
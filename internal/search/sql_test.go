@@ -0,0 +1,85 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var testSchema = Schema{
+	Text:  func(arg string) string { return "text @@ " + arg },
+	Tag:   func(arg string) string { return "tag(" + arg + ")" },
+	Flags: map[string]string{"archived": "n.archived", "pinned": "n.pinned"},
+	Dates: map[string]string{"created": "n.created_at", "updated": "n.updated_at"},
+}
+
+func TestSQL(t *testing.T) {
+	tests := []struct {
+		input     string
+		argOffset int
+		wantSQL   string
+		wantArgs  []any
+	}{
+		{"", 0, "TRUE", nil},
+		{"a", 0, "(text @@ $1)", []any{"a"}},
+		{"a OR b c", 2, "(text @@ $3 OR text @@ $4) AND (text @@ $5)", []any{"a", "b", "c"}},
+		{`"cash flow"`, 0, "(text @@ $1)", []any{`"cash flow"`}},
+		{"-tag:old is:pinned", 0, "(NOT (tag($1))) AND (n.pinned)", []any{"old"}},
+		{"-is:archived", 0, "(NOT (n.archived))", nil},
+		{"created:>2025-01-01", 0, "(n.created_at >= $1)", []any{date("2025-01-02")}},
+		{"created:>=2025-01-01", 0, "(n.created_at >= $1)", []any{date("2025-01-01")}},
+		{"created:<2025-01-01", 0, "(n.created_at < $1)", []any{date("2025-01-01")}},
+		{"created:<=2025-01-01", 0, "(n.created_at < $1)", []any{date("2025-01-02")}},
+		{"updated:2025-01-31", 1, "((n.updated_at >= $2 AND n.updated_at < $3))", []any{date("2025-01-31"), date("2025-02-01")}},
+		{"-before:2025-01-01 OR x", 0, "(NOT (n.created_at < $1) OR text @@ $2)", []any{date("2025-01-01"), "x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sql, args, err := query.SQL(testSchema, tt.argOffset)
+			if err != nil {
+				t.Fatalf("SQL() returned error: %v", err)
+			}
+			if sql != tt.wantSQL {
+				t.Errorf("SQL() = %q, want %q", sql, tt.wantSQL)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("SQL() args = %v, want %v", args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestSQLUnsupportedFilters(t *testing.T) {
+	schema := Schema{Text: testSchema.Text, Tag: testSchema.Tag}
+
+	tests := []struct {
+		input string
+		param string
+	}{
+		{"is:archived", "is:archived"},
+		{"a OR -is:pinned", "is:pinned"},
+		{"updated:>2025-01-01", "updated:2025-01-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			query, err := Parse(tt.input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, _, err = query.SQL(schema, 0)
+			var searchErr *Error
+			if !errors.As(err, &searchErr) {
+				t.Fatalf("SQL() error = %v, want a search error", err)
+			}
+			if searchErr.Key != "search.error.unsupported_filter" || searchErr.Param != tt.param {
+				t.Errorf("SQL() error = %+v, want unsupported_filter for %q", *searchErr, tt.param)
+			}
+		})
+	}
+}
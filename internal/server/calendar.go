@@ -0,0 +1,124 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/auth"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/ical"
+)
+
+// calendarFeedHistory is how far back the feed reaches, so that calendar
+// apps keep showing recent items after they are due.
+const calendarFeedHistory = 30 * 24 * time.Hour
+
+// calendarFeedURL returns the address of the feed served for token.
+func (s *Server) calendarFeedURL(locale, token string) string {
+	return strings.TrimSuffix(s.cfg.BaseURL, "/") + "/" + locale + "/calendar/" + token + ".ics"
+}
+
+// noteURL returns the address of the dashboard with the note open.
+func (s *Server) noteURL(locale string, id uuid.UUID) string {
+	return strings.TrimSuffix(s.cfg.BaseURL, "/") + "/" + locale + "/dashboard?note=" + id.String()
+}
+
+// createCalendarToken issues a new calendar feed token for the current
+// user, revoking the previous one. The token is only ever shown in this
+// response.
+func (s *Server) createCalendarToken(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	token, err := auth.NewToken()
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	hash := auth.HashToken(token)
+	if err := s.store.Users.SetCalendarTokenHash(r.Context(), userID, &hash); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, map[string]string{
+		"token": token,
+		"url":   s.calendarFeedURL(r.Context().Value(localeKey).(string), token),
+	})
+}
+
+// deleteCalendarToken revokes the calendar feed token of the current user.
+func (s *Server) deleteCalendarToken(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	if err := s.store.Users.SetCalendarTokenHash(r.Context(), userID, nil); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getCalendarFeed serves the due dates and reminders of the user owning the
+// token in the URL as an iCalendar feed. Calendar apps fetch it without a
+// session, so the token alone authenticates the request.
+func (s *Server) getCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	locale := r.Context().Value(localeKey).(string)
+
+	user, err := s.store.Users.GetByCalendarTokenHash(r.Context(), auth.HashToken(chi.URLParam(r, "token")))
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if user == nil {
+		s.errorJSON(w, errors.New("calendar not found"), http.StatusNotFound)
+		return
+	}
+
+	notes, err := s.store.Notes.GetScheduled(r.Context(), user.ID, time.Now().Add(-calendarFeedHistory))
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Event UIDs are scoped to the host so they stay unique across servers.
+	host := "mangocatnotes"
+	if base, err := url.Parse(s.cfg.BaseURL); err == nil && base.Hostname() != "" {
+		host = base.Hostname()
+	}
+
+	// DTSTAMP is when this copy of the feed was made, in UTC.
+	generatedAt := time.Now().UTC()
+	cal := ical.Calendar{
+		ProdID: "-//Mangocat Notes//Calendar//" + strings.ToUpper(locale),
+		Name:   s.i18n.Translate(locale, "calendar.name", map[string]any{"Username": user.Username}),
+	}
+	for _, note := range notes {
+		link := s.noteURL(locale, note.ID)
+		event := ical.Event{
+			UID:         note.ID.String() + "@" + host,
+			Stamp:       generatedAt,
+			Summary:     note.Title,
+			Description: link,
+			URL:         link,
+			Alarm:       note.RemindAt,
+		}
+		// A note with only a reminder shows up at the time of the reminder.
+		if note.DueAt != nil {
+			event.Start = *note.DueAt
+		} else {
+			event.Start = *note.RemindAt
+		}
+		cal.Events = append(cal.Events, event)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if err := ical.Write(w, cal); err != nil {
+		s.logger.Errorw("failed to write calendar feed", "user", user.ID, "error", err)
+	}
+}
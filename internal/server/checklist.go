@@ -0,0 +1,104 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/checklist"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+)
+
+func (s *Server) getNoteChecklist(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("ETag", noteETag(note))
+	s.writeJSON(w, http.StatusOK, checklist.Parse(note.Content))
+}
+
+// updateNoteChecklistItem checks or unchecks one checklist item, toggling it
+// when the body does not say which. The change is saved like any other edit
+// of the content, so it gets a revision and a new version.
+func (s *Server) updateNoteChecklistItem(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && !matchesETag(ifMatch, noteETag(note)) {
+		s.noteConflict(w, r, note)
+		return
+	}
+
+	index, err := strconv.Atoi(chi.URLParam(r, "index"))
+	if err != nil || index < 0 {
+		s.errorJSON(w, errors.New("invalid checklist index"), http.StatusBadRequest)
+		return
+	}
+
+	items := checklist.Parse(note.Content).Items
+	if index >= len(items) {
+		s.errorJSON(w, errors.New("checklist item not found"), http.StatusNotFound)
+		return
+	}
+	checked := !items[index].Checked
+
+	if strings.Contains(r.Header.Get("Content-Type"), "json") {
+		var input struct {
+			Checked *bool `json:"checked"`
+		}
+		if r.ContentLength != 0 {
+			if err := s.readJSON(w, r, &input); err != nil {
+				s.errorJSON(w, err, http.StatusBadRequest)
+				return
+			}
+		}
+		if input.Checked != nil {
+			checked = *input.Checked
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			s.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		if value := r.PostForm.Get("checked"); value != "" {
+			if checked, err = strconv.ParseBool(value); err != nil {
+				s.errorJSON(w, errors.New("invalid checked value"), http.StatusBadRequest)
+				return
+			}
+		}
+	}
+
+	if items[index].Checked != checked {
+		content, _ := checklist.Set(note.Content, index, checked)
+		if err := s.store.Notes.Patch(r.Context(), note, store.NotePatch{Content: &content}); err != nil {
+			if errors.Is(err, store.ErrVersionConflict) {
+				s.currentNoteConflict(w, r, note.ID)
+				return
+			}
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("ETag", noteETag(note))
+	if r.Header.Get("HX-Request") != "" {
+		tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		note.Tags = tags
+
+		w.Header().Set("Content-Type", "text/html")
+		s.renderBlock(w, r, "note-card", s.noteCardData(r, note))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, checklist.Parse(note.Content))
+}
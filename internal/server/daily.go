@@ -0,0 +1,180 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+)
+
+const (
+	dailyDateLayout  = "2006-01-02"
+	dailyMonthLayout = "2006-01"
+)
+
+// today returns the current day in loc as a date at midnight UTC, the form
+// daily dates are stored in.
+func today(loc *time.Location) time.Time {
+	now := time.Now().In(loc)
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// getDailyNote answers with the daily note for the date URL parameter, or
+// for the current day when it is "today", creating the note from the daily
+// template of the user when the day has none yet. A daily note in the trash
+// is restored instead of replaced.
+func (s *Server) getDailyNote(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	loc, err := s.userLocation(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	date := today(loc)
+	if value := chi.URLParam(r, "date"); value != "today" {
+		if date, err = time.Parse(dailyDateLayout, value); err != nil {
+			s.errorJSON(w, errors.New("invalid date, expected YYYY-MM-DD"), http.StatusBadRequest)
+			return
+		}
+	}
+
+	note, err := s.store.Notes.GetDaily(r.Context(), userID, date)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	status := http.StatusOK
+	if note == nil {
+		var created bool
+		if note, created, err = s.createDailyNote(r, userID, date, loc); err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		if created {
+			status = http.StatusCreated
+		}
+	}
+
+	if note.DeletedAt != nil {
+		if err := s.store.Notes.Restore(r.Context(), note.ID); err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		note.DeletedAt = nil
+	}
+
+	tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	note.Tags = tags
+
+	s.writeJSON(w, status, note)
+}
+
+// createDailyNote creates the daily note for date, expanding its template
+// at the time of day in loc. When a concurrent request gets there first, the
+// note it created is returned instead and created is false.
+func (s *Server) createDailyNote(r *http.Request, userID uuid.UUID, date time.Time, loc *time.Location) (*models.Note, bool, error) {
+	tmpl, err := s.dailyTemplate(r.Context(), userID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	title := date.Format(dailyDateLayout)
+	note := models.Note{UserID: userID, Title: title}
+	var tagNames []string
+	if tmpl != nil {
+		// Placeholders see the day of the note at the current time of day.
+		now := time.Now().In(loc)
+		at := time.Date(date.Year(), date.Month(), date.Day(), now.Hour(), now.Minute(), now.Second(), 0, loc)
+
+		rendered := renderNoteTemplate(tmpl, title, at)
+		note.Title = rendered.Title
+		note.Content = rendered.Content
+		note.Language = rendered.Language
+		tagNames = rendered.Tags
+	}
+
+	if note.Language, err = s.noteLanguage(r, &note); err != nil {
+		return nil, false, err
+	}
+
+	created, err := s.store.Notes.CreateDaily(r.Context(), &note, date, tagNames)
+	if err != nil {
+		return nil, false, err
+	}
+	if !created {
+		existing, err := s.store.Notes.GetDaily(r.Context(), userID, date)
+		if err != nil {
+			return nil, false, err
+		}
+		if existing == nil {
+			return nil, false, errors.New("daily note disappeared while being created")
+		}
+		return existing, false, nil
+	}
+
+	return &note, true, nil
+}
+
+// dailyTemplate returns the template the user picked for daily notes, or
+// nil when there is none.
+func (s *Server) dailyTemplate(ctx context.Context, userID uuid.UUID) (*models.NoteTemplate, error) {
+	user, err := s.store.Users.GetByID(ctx, userID)
+	if err != nil || user == nil || user.DailyTemplateID == nil {
+		return nil, err
+	}
+
+	tmpl, err := s.store.Templates.GetByID(ctx, *user.DailyTemplateID)
+	if err != nil || tmpl == nil || tmpl.UserID != userID {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// getDailyDates lists the days of the month query parameter, the current
+// one by default, that have a daily note.
+func (s *Server) getDailyDates(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	loc, err := s.userLocation(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	first := today(loc)
+	first = first.AddDate(0, 0, 1-first.Day())
+	if value := r.URL.Query().Get("month"); value != "" {
+		if first, err = time.Parse(dailyMonthLayout, value); err != nil {
+			s.errorJSON(w, errors.New("invalid month, expected YYYY-MM"), http.StatusBadRequest)
+			return
+		}
+	}
+	last := first.AddDate(0, 1, -1)
+
+	dates, err := s.store.Notes.GetDailyDates(r.Context(), userID, first, last)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	days := make([]string, 0, len(dates))
+	for _, date := range dates {
+		days = append(days, date.Format(dailyDateLayout))
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"month": first.Format(dailyMonthLayout),
+		"data":  days,
+	})
+}
@@ -0,0 +1,97 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+)
+
+const maxGraphDepth = 5
+
+func parseGraphDate(value string) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	date, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid date (%s)", value)
+	}
+	return &date, nil
+}
+
+func (s *Server) getNoteGraph(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+	params := r.URL.Query()
+
+	from, err := parseGraphDate(params.Get("from"))
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	to, err := parseGraphDate(params.Get("to"))
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if to != nil {
+		// The range includes the whole of its last day.
+		end := to.AddDate(0, 0, 1)
+		to = &end
+	}
+
+	g, err := s.store.Notes.GetGraph(r.Context(), userID, store.GraphFilter{
+		Tags: params["tags"],
+		From: from,
+		To:   to,
+	})
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if focusParam := params.Get("focus"); focusParam != "" {
+		focus, err := uuid.Parse(focusParam)
+		if err != nil {
+			s.errorJSON(w, errors.New("invalid focus id"), http.StatusBadRequest)
+			return
+		}
+
+		depth := 1
+		if depthParam := params.Get("depth"); depthParam != "" {
+			depth, err = strconv.Atoi(depthParam)
+			if err != nil || depth < 1 || depth > maxGraphDepth {
+				s.errorJSON(w, fmt.Errorf("depth must be between 1 and %d", maxGraphDepth), http.StatusBadRequest)
+				return
+			}
+		}
+
+		var ok bool
+		if g, ok = g.Around(focus, depth); !ok {
+			s.errorJSON(w, errors.New("focus note not found in graph"), http.StatusNotFound)
+			return
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, g)
+}
+
+func (s *Server) graphPage(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	userTags, _, err := s.store.Tags.GetAll(r.Context(), userID, allTags, store.TagFilter{})
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.render(w, r, "graph.html", map[string]any{
+		"Title":         "graph.title",
+		"AvailableTags": userTags,
+		"MaxDepth":      maxGraphDepth,
+	})
+}
@@ -1,11 +1,16 @@
 package server
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/gorilla/schema"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
 )
 
 var decoder *schema.Decoder
@@ -43,6 +48,70 @@ func (s *Server) readJSON(w http.ResponseWriter, r *http.Request, data any) erro
 	return nil
 }
 
+// readMergePatch reads a JSON merge patch (RFC 7396) into its members, so
+// that fields left out of the body can be told apart from explicit nulls.
+// Members other than fields are rejected.
+func (s *Server) readMergePatch(w http.ResponseWriter, r *http.Request, fields ...string) (map[string]json.RawMessage, error) {
+	var members map[string]json.RawMessage
+	if err := s.readJSON(w, r, &members); err != nil {
+		return nil, err
+	}
+	if members == nil {
+		return nil, errors.New("body must be a JSON object")
+	}
+
+	for key := range members {
+		if !slices.Contains(fields, key) {
+			return nil, fmt.Errorf("json: unknown field %q", key)
+		}
+	}
+
+	return members, nil
+}
+
+func isJSONNull(raw json.RawMessage) bool {
+	return string(bytes.TrimSpace(raw)) == "null"
+}
+
+// patchString decodes a merge patch member that cannot be removed, returning
+// nil when it was left out.
+func patchString(members map[string]json.RawMessage, key string) (*string, error) {
+	raw, ok := members[key]
+	if !ok {
+		return nil, nil
+	}
+	if isJSONNull(raw) {
+		return nil, fmt.Errorf("%s cannot be null", key)
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return &value, nil
+}
+
+// readPage reads the pagination query parameters. A cursor parameter,
+// even an empty one, selects keyset pagination and page is ignored.
+func readPage(r *http.Request, defaultLimit int64) store.Page {
+	query := r.URL.Query()
+
+	limit, _ := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if limit < 1 {
+		limit = defaultLimit
+	}
+
+	if _, ok := query["cursor"]; ok {
+		return store.Page{Limit: limit, Cursor: query.Get("cursor"), Keyset: true}
+	}
+
+	number, _ := strconv.ParseInt(query.Get("page"), 10, 64)
+	if number < 1 {
+		number = 1
+	}
+	return store.Page{Number: number, Limit: limit}
+}
+
 func (s *Server) decodeForm(r *http.Request, dst any) error {
 	if err := r.ParseForm(); err != nil {
 		return err
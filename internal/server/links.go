@@ -0,0 +1,84 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+)
+
+func (s *Server) getNoteLinks(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	links, err := s.store.Notes.GetLinks(r.Context(), note.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if links == nil {
+		links = []models.NoteLink{}
+	}
+
+	dangling := 0
+	for _, link := range links {
+		if link.Dangling {
+			dangling++
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"data":     links,
+		"count":    len(links),
+		"dangling": dangling,
+	})
+}
+
+func (s *Server) getNoteBacklinks(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	backlinks, err := s.store.Notes.GetBacklinks(r.Context(), note.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if backlinks == nil {
+		backlinks = []models.Backlink{}
+	}
+
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("Content-Type", "text/html")
+		s.renderBlock(w, r, "backlinks_panel", map[string]any{
+			"Backlinks": backlinks,
+		})
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"data":  backlinks,
+		"count": len(backlinks),
+	})
+}
+
+func (s *Server) getDanglingLinks(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	links, err := s.store.Notes.GetDanglingLinks(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if links == nil {
+		links = []models.NoteLink{}
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"data":  links,
+		"count": len(links),
+	})
+}
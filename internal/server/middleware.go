@@ -4,6 +4,9 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"regexp"
+
+	"github.com/go-chi/chi/v5/middleware"
 )
 
 type contextKey string
@@ -66,3 +69,31 @@ func (s *Server) securityHeaders(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r)
 	})
 }
+
+const unredactedRequestKey contextKey = "unredactedRequest"
+
+// calendarTokenPattern matches the secret in calendar feed URLs.
+var calendarTokenPattern = regexp.MustCompile(`(/calendar/)[^/?]+(\.ics)`)
+
+// requestLogger logs requests like middleware.Logger, but with calendar feed
+// tokens blanked out, since only their hash is meant to be kept anywhere.
+// The handlers still see the request as it came in.
+func requestLogger(next http.Handler) http.Handler {
+	logged := middleware.Logger(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if original, ok := r.Context().Value(unredactedRequestKey).(*http.Request); ok {
+			r = original.WithContext(r.Context())
+		}
+		next.ServeHTTP(w, r)
+	}))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !calendarTokenPattern.MatchString(r.RequestURI) {
+			logged.ServeHTTP(w, r)
+			return
+		}
+
+		redacted := r.Clone(context.WithValue(r.Context(), unredactedRequestKey, r))
+		redacted.RequestURI = calendarTokenPattern.ReplaceAllString(r.RequestURI, "${1}REDACTED${2}")
+		logged.ServeHTTP(w, redacted)
+	})
+}
@@ -0,0 +1,247 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+)
+
+var errNotebookNotFound = errors.New("notebook not found")
+
+func (s *Server) getNotebooks(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	notebooks, err := s.store.Notebooks.GetAll(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"data":  notebooks,
+		"count": len(notebooks),
+	})
+}
+
+func (s *Server) getNotebook(w http.ResponseWriter, r *http.Request) {
+	notebook, ok := s.ownedNotebook(w, r)
+	if !ok {
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, notebook)
+}
+
+func (s *Server) createNotebook(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	var input struct {
+		Name     string     `json:"name" validate:"required,max=100"`
+		ParentID *uuid.UUID `json:"parentId"`
+	}
+	if err := s.readJSON(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := s.validateStruct(input); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, s.formatValidationErrors(err))
+		return
+	}
+
+	if input.ParentID != nil {
+		if ok, err := s.userHasNotebook(r.Context(), userID, *input.ParentID); err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		} else if !ok {
+			s.errorJSON(w, errors.New("parent notebook not found"), http.StatusNotFound)
+			return
+		}
+	}
+
+	notebook := models.Notebook{
+		UserID:   userID,
+		ParentID: input.ParentID,
+		Name:     input.Name,
+	}
+	if err := s.store.Notebooks.Create(r.Context(), &notebook); err != nil {
+		if errors.Is(err, store.ErrNotebookExists) {
+			s.errorJSON(w, err, http.StatusConflict)
+			return
+		}
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, notebook)
+}
+
+func (s *Server) updateNotebook(w http.ResponseWriter, r *http.Request) {
+	notebook, ok := s.ownedNotebook(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		Name string `json:"name" validate:"required,max=100"`
+	}
+	if err := s.readJSON(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := s.validateStruct(input); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, s.formatValidationErrors(err))
+		return
+	}
+
+	if err := s.store.Notebooks.Rename(r.Context(), notebook, input.Name); err != nil {
+		if errors.Is(err, store.ErrNotebookExists) {
+			s.errorJSON(w, err, http.StatusConflict)
+			return
+		}
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, notebook)
+}
+
+// moveNotebook puts the notebook under another one, or at the root when
+// parentId is null.
+func (s *Server) moveNotebook(w http.ResponseWriter, r *http.Request) {
+	notebook, ok := s.ownedNotebook(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		ParentID *uuid.UUID `json:"parentId"`
+	}
+	if err := s.readJSON(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if input.ParentID != nil {
+		if ok, err := s.userHasNotebook(r.Context(), notebook.UserID, *input.ParentID); err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		} else if !ok {
+			s.errorJSON(w, errors.New("parent notebook not found"), http.StatusNotFound)
+			return
+		}
+	}
+
+	if err := s.store.Notebooks.Move(r.Context(), notebook, input.ParentID); err != nil {
+		switch {
+		case errors.Is(err, store.ErrNotebookCycle):
+			s.errorJSON(w, err, http.StatusBadRequest)
+		case errors.Is(err, store.ErrNotebookExists):
+			s.errorJSON(w, err, http.StatusConflict)
+		default:
+			s.errorJSON(w, err, http.StatusInternalServerError)
+		}
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, notebook)
+}
+
+// deleteNotebook removes the notebook with its descendants. The mode query
+// parameter picks where their notes go: the root by default, or the trash
+// with mode=cascade.
+func (s *Server) deleteNotebook(w http.ResponseWriter, r *http.Request) {
+	notebook, ok := s.ownedNotebook(w, r)
+	if !ok {
+		return
+	}
+
+	mode := store.NotebookDeleteMode(r.URL.Query().Get("mode"))
+	switch mode {
+	case "":
+		mode = store.NotebookDeleteToRoot
+	case store.NotebookDeleteToRoot, store.NotebookDeleteCascade:
+	default:
+		s.errorJSON(w, fmt.Errorf("invalid delete mode (%s)", mode), http.StatusBadRequest)
+		return
+	}
+
+	if err := s.store.Notebooks.Delete(r.Context(), notebook.ID, mode); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// moveNote files the note in a notebook, or at the root when notebookId is
+// null.
+func (s *Server) moveNote(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	var input struct {
+		NotebookID *uuid.UUID `json:"notebookId"`
+	}
+	if err := s.readJSON(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if input.NotebookID != nil {
+		if ok, err := s.userHasNotebook(r.Context(), note.UserID, *input.NotebookID); err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		} else if !ok {
+			s.errorJSON(w, errNotebookNotFound, http.StatusNotFound)
+			return
+		}
+	}
+
+	if err := s.store.Notes.SetNotebook(r.Context(), note.ID, input.NotebookID); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	note.NotebookID = input.NotebookID
+
+	s.writeJSON(w, http.StatusOK, note)
+}
+
+// ownedNotebook loads the notebook in the id URL parameter and checks that
+// it belongs to the current user, writing the error response itself when
+// it cannot.
+func (s *Server) ownedNotebook(w http.ResponseWriter, r *http.Request) (*models.Notebook, bool) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	notebook, err := s.store.Notebooks.GetByID(r.Context(), id)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return nil, false
+	}
+	if notebook == nil || notebook.UserID != userID {
+		s.errorJSON(w, errNotebookNotFound, http.StatusNotFound)
+		return nil, false
+	}
+
+	return notebook, true
+}
+
+func (s *Server) userHasNotebook(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	notebook, err := s.store.Notebooks.GetByID(ctx, id)
+	if err != nil {
+		return false, err
+	}
+	return notebook != nil && notebook.UserID == userID, nil
+}
@@ -3,70 +3,163 @@ package server
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"html/template"
 	"net/http"
+	"slices"
 	"strconv"
 
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/checklist"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/language"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/search"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
 )
 
+func (s *Server) noteCardData(r *http.Request, note *models.Note) map[string]any {
+	// The card falls back to the plain text when rendering fails.
+	contentHTML, err := s.markdown.Note(r.Context(), note)
+	if err != nil {
+		s.logger.Errorw("failed to render note content", "note", note.ID, "error", err)
+	}
+
+	return map[string]any{
+		"ID":               note.ID,
+		"Title":            note.Title,
+		"Content":          note.Content,
+		"ContentHTML":      template.HTML(contentHTML),
+		"Checklist":        checklist.Parse(note.Content),
+		"TitleHighlight":   note.TitleHighlight,
+		"ContentHighlight": note.ContentHighlight,
+		"UpdatedAt":        note.UpdatedAt,
+		"Archived":         note.Archived,
+		"Pinned":           note.Pinned,
+		"DeletedAt":        note.DeletedAt,
+		"DueAt":            note.DueAt,
+		"Overdue":          note.DueAt != nil && note.DueAt.Before(time.Now()),
+		"Tags":             note.Tags,
+		"Lang":             r.Context().Value(localeKey),
+	}
+}
+
+// noteLanguage returns the language chosen for the note, detecting it from
+// the text when the user left it on automatic.
+func (s *Server) noteLanguage(r *http.Request, note *models.Note) (string, error) {
+	if note.Language != "" && note.Language != "auto" {
+		if !language.IsSupported(note.Language) {
+			return "", fmt.Errorf("unsupported language (%s)", note.Language)
+		}
+		return note.Language, nil
+	}
+
+	locale := r.Context().Value(localeKey).(string)
+	return language.Detect(note.Title+"\n"+note.Content, locale), nil
+}
+
+// createNotePage renders the form for a new note. With template it is
+// filled in from that template, and title stands for its {{title}}.
 func (s *Server) createNotePage(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserIDKey).(uuid.UUID)
 
-	userTags, _, err := s.store.Tags.GetAll(r.Context(), userID, 1, 1000)
+	userTags, _, err := s.store.Tags.GetAll(r.Context(), userID, allTags, store.TagFilter{})
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	templates, err := s.store.Templates.GetAll(r.Context(), userID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	prefill := renderedNoteTemplate{Language: "auto"}
+	var templateID uuid.UUID
+	if value := r.URL.Query().Get("template"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			s.errorJSON(w, errors.New("invalid template id"), http.StatusBadRequest)
+			return
+		}
+		index := slices.IndexFunc(templates, func(tmpl models.NoteTemplate) bool { return tmpl.ID == id })
+		if index < 0 {
+			s.errorJSON(w, errNoteTemplateNotFound, http.StatusNotFound)
+			return
+		}
+		// {{date}} and {{time}} are the user's, not the server's.
+		loc, err := s.userLocation(r.Context(), userID)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		templateID = id
+		prefill = renderNoteTemplate(&templates[index], r.URL.Query().Get("title"), time.Now().In(loc))
+	}
+
+	// Default tags the user does not have yet are created by FindOrCreate
+	// when the note is saved, like any other new tag typed in the form.
+	selectedTags := []map[string]any{}
+	for i, name := range prefill.Tags {
+		index := slices.IndexFunc(userTags, func(tag models.Tag) bool { return tag.Name == name })
+		if index >= 0 {
+			selectedTags = append(selectedTags, map[string]any{"id": userTags[index].ID, "name": name})
+		} else {
+			selectedTags = append(selectedTags, map[string]any{"id": fmt.Sprintf("new-%d", i), "name": name, "isNew": true})
+		}
+	}
+
 	s.renderBlock(w, r, "create_note_modal", map[string]any{
 		"AvailableTags": userTags,
+		"Templates":     templates,
+		"TemplateID":    templateID,
+		"Prefill":       prefill,
+		"SelectedTags":  selectedTags,
 	})
 }
 
 func (s *Server) editNotePage(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value(UserIDKey).(uuid.UUID)
-	idParam := chi.URLParam(r, "id")
-	id, err := uuid.Parse(idParam)
-	if err != nil {
-		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+	note, ok := s.ownedNote(w, r)
+	if !ok {
 		return
 	}
 
-	note, err := s.store.Notes.GetByID(r.Context(), id)
+	noteTags, err := s.store.Notes.GetTags(r.Context(), note.ID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
-	if note == nil {
-		s.errorJSON(w, errors.New("note not found"), http.StatusNotFound)
-		return
-	}
-
-	if note.UserID != userID {
-		s.errorJSON(w, errors.New("forbidden"), http.StatusForbidden)
-		return
-	}
+	note.Tags = noteTags
 
-	noteTags, err := s.store.Notes.GetTags(r.Context(), note.ID)
+	userTags, _, err := s.store.Tags.GetAll(r.Context(), note.UserID, allTags, store.TagFilter{})
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
-	note.Tags = noteTags
 
-	userTags, _, err := s.store.Tags.GetAll(r.Context(), note.UserID, 1, 1000)
+	loc, err := s.userLocation(r.Context(), note.UserID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
+	// The schedule inputs show wall clock times in the user's timezone.
+	schedule := map[string]string{"Timezone": loc.String(), "DueAt": "", "RemindAt": ""}
+	if note.DueAt != nil {
+		schedule["DueAt"] = note.DueAt.In(loc).Format(scheduleLocalLayout)
+	}
+	if note.RemindAt != nil {
+		schedule["RemindAt"] = note.RemindAt.In(loc).Format(scheduleLocalLayout)
+	}
+
 	s.renderBlock(w, r, "edit_note_modal", map[string]any{
 		"Note":          note,
 		"AvailableTags": userTags,
+		"Schedule":      schedule,
 	})
 }
 
@@ -88,6 +181,7 @@ func (s *Server) createNote(w http.ResponseWriter, r *http.Request) {
 		}
 		input.Title = r.FormValue("title")
 		input.Content = r.FormValue("content")
+		input.Language = r.FormValue("language")
 
 		tagsJSON := r.FormValue("tags")
 		if tagsJSON != "" && tagsJSON != "[]" {
@@ -98,6 +192,23 @@ func (s *Server) createNote(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	language, err := s.noteLanguage(r, &input)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	input.Language = language
+
+	if input.NotebookID != nil {
+		if ok, err := s.userHasNotebook(r.Context(), userID, *input.NotebookID); err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		} else if !ok {
+			s.errorJSON(w, errNotebookNotFound, http.StatusNotFound)
+			return
+		}
+	}
+
 	input.UserID = userID
 	if err := s.store.Notes.Create(r.Context(), &input); err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
@@ -132,14 +243,7 @@ func (s *Server) createNote(w http.ResponseWriter, r *http.Request) {
 	if r.Header.Get("HX-Request") != "" {
 		w.Header().Set("Content-Type", "text/html")
 		w.Header().Set("HX-Trigger", "note-created")
-		data := map[string]any{
-			"Title":     input.Title,
-			"Content":   input.Content,
-			"ID":        input.ID,
-			"UpdatedAt": input.UpdatedAt,
-			"Tags":      input.Tags,
-		}
-		s.renderBlock(w, r, "note-card", data)
+		s.renderBlock(w, r, "note-card", s.noteCardData(r, &input))
 		w.Write([]byte(`<div id="empty-state" hx-swap-oob="delete"></div>`))
 		w.Write([]byte(`<div id="notes-grid" hx-swap-oob="removeClass:hidden"></div>`))
 		return
@@ -150,20 +254,61 @@ func (s *Server) createNote(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) getNotes(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserIDKey).(uuid.UUID)
-	page, _ := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
-	if page < 1 {
-		page = 1
+	page := readPage(r, 10)
+
+	query, err := search.Parse(r.URL.Query().Get("search"))
+	if err != nil {
+		s.searchErrorJSON(w, r, err)
+		return
 	}
-	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
-	if limit < 1 {
-		limit = 10
+	tags := r.URL.Query()["tags"]
+	match, err := parseTagMatch(r.URL.Query().Get("match"))
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
 	}
 
-	search := r.URL.Query().Get("search")
-	tags := r.URL.Query()["tags"]
+	archived, err := parseArchiveFilter(r.URL.Query().Get("archived"))
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	sort, err := parseNoteSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
 
-	notes, count, err := s.store.Notes.GetAll(r.Context(), userID, int64(page), int64(limit), search, tags)
+	var notebook *uuid.UUID
+	if value := r.URL.Query().Get("notebook"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			s.errorJSON(w, errors.New("invalid notebook id"), http.StatusBadRequest)
+			return
+		}
+		notebook = &id
+	}
+	descendants, _ := strconv.ParseBool(r.URL.Query().Get("descendants"))
+
+	notes, meta, err := s.store.Notes.GetAll(r.Context(), userID, page, store.NoteFilter{
+		Query:               query,
+		Tags:                tags,
+		TagMatch:            match,
+		Locale:              r.Context().Value(localeKey).(string),
+		Archived:            archived,
+		Notebook:            notebook,
+		NotebookDescendants: descendants,
+		Sort:                sort,
+	})
 	if err != nil {
+		if s.searchErrorJSON(w, r, err) {
+			return
+		}
+		if errors.Is(err, store.ErrInvalidCursor) {
+			s.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
@@ -183,39 +328,171 @@ func (s *Server) getNotes(w http.ResponseWriter, r *http.Request) {
 
 	s.writeJSON(w, http.StatusOK, models.PaginatedNotesResponse{
 		Data: notes,
-		Meta: models.PaginationMetadata{
-			Page:       (page),
-			Limit:      (limit),
-			Count:      count,
-			TotalPages: (count + limit - 1) / limit,
-		},
+		Meta: meta,
 	})
 }
 
 func (s *Server) getNote(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value(UserIDKey).(uuid.UUID)
-	idParam := chi.URLParam(r, "id")
-	id, err := uuid.Parse(idParam)
-	if err != nil {
-		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+	note, ok := s.ownedNote(w, r)
+	if !ok {
 		return
 	}
 
-	note, err := s.store.Notes.GetByID(r.Context(), id)
+	tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
-	if note == nil {
-		s.errorJSON(w, errors.New("note not found"), http.StatusNotFound)
+	note.Tags = tags
+
+	w.Header().Set("ETag", noteETag(note))
+
+	switch r.URL.Query().Get("format") {
+	case "", "json":
+		s.writeJSON(w, http.StatusOK, note)
+	case "html":
+		contentHTML, err := s.markdown.Note(r.Context(), note)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(contentHTML))
+	default:
+		s.errorJSON(w, fmt.Errorf("unsupported format (%s)", r.URL.Query().Get("format")), http.StatusBadRequest)
+	}
+}
+
+// noteUpdate is the change asked for by a PATCH on a note.
+type noteUpdate struct {
+	patch store.NotePatch
+}
+
+func (u noteUpdate) isEmpty() bool {
+	return u.patch == store.NotePatch{}
+}
+
+// readNoteUpdate reads a JSON merge patch or, from the edit form, the fields
+// it posted. A null language goes back to automatic detection and null tags
+// remove them all.
+func (s *Server) readNoteUpdate(w http.ResponseWriter, r *http.Request) (noteUpdate, error) {
+	var update noteUpdate
+
+	if strings.Contains(r.Header.Get("Content-Type"), "json") {
+		members, err := s.readMergePatch(w, r, "title", "content", "language", "tags")
+		if err != nil {
+			return update, err
+		}
+
+		if update.patch.Title, err = patchString(members, "title"); err != nil {
+			return update, err
+		}
+		if update.patch.Content, err = patchString(members, "content"); err != nil {
+			return update, err
+		}
+
+		if raw, ok := members["language"]; ok {
+			language := "auto"
+			if !isJSONNull(raw) {
+				if err := json.Unmarshal(raw, &language); err != nil {
+					return update, fmt.Errorf("invalid language: %w", err)
+				}
+			}
+			update.patch.Language = &language
+		}
+
+		if raw, ok := members["tags"]; ok {
+			tagNames := []string{}
+			if !isJSONNull(raw) {
+				if err := json.Unmarshal(raw, &tagNames); err != nil {
+					return update, fmt.Errorf("invalid tags: %w", err)
+				}
+			}
+			update.patch.Tags = &tagNames
+		}
+
+		return update, nil
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return update, err
+	}
+
+	formValue := func(key string) *string {
+		if _, ok := r.PostForm[key]; !ok {
+			return nil
+		}
+		value := r.PostForm.Get(key)
+		return &value
+	}
+	update.patch.Title = formValue("title")
+	update.patch.Content = formValue("content")
+	update.patch.Language = formValue("language")
+
+	if tagsJSON := formValue("tags"); tagsJSON != nil {
+		tagNames := []string{}
+		if *tagsJSON != "" {
+			if err := json.Unmarshal([]byte(*tagsJSON), &tagNames); err != nil {
+				return update, err
+			}
+		}
+		update.patch.Tags = &tagNames
+	}
+
+	return update, nil
+}
+
+func (s *Server) updateNote(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
 		return
 	}
 
-	if note.UserID != userID {
-		s.errorJSON(w, errors.New("forbidden"), http.StatusForbidden)
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		s.errorJSON(w, errors.New("missing If-Match header"), http.StatusPreconditionRequired)
+		return
+	}
+	if !matchesETag(ifMatch, noteETag(note)) {
+		s.noteConflict(w, r, note)
 		return
 	}
 
+	update, err := s.readNoteUpdate(w, r)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if update.patch.Language != nil {
+		// Detect the language on the text the note will have once patched.
+		merged := models.Note{Title: note.Title, Content: note.Content, Language: *update.patch.Language}
+		if update.patch.Title != nil {
+			merged.Title = *update.patch.Title
+		}
+		if update.patch.Content != nil {
+			merged.Content = *update.patch.Content
+		}
+
+		language, err := s.noteLanguage(r, &merged)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		update.patch.Language = &language
+	}
+
+	if !update.isEmpty() {
+		if err := s.store.Notes.Patch(r.Context(), note, update.patch); err != nil {
+			if errors.Is(err, store.ErrVersionConflict) {
+				s.currentNoteConflict(w, r, note.ID)
+				return
+			}
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
 	tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
@@ -223,159 +500,385 @@ func (s *Server) getNote(w http.ResponseWriter, r *http.Request) {
 	}
 	note.Tags = tags
 
+	w.Header().Set("ETag", noteETag(note))
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("HX-Trigger", "note-updated")
+		s.renderBlock(w, r, "note-card", s.noteCardData(r, note))
+		return
+	}
+
 	s.writeJSON(w, http.StatusOK, note)
 }
 
-func (s *Server) updateNote(w http.ResponseWriter, r *http.Request) {
+func (s *Server) deleteNote(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.Notes.Delete(r.Context(), note.ID); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.removedCard(w, r, "note-trashed")
+}
+
+func parseNoteSort(value string) (store.NoteSort, error) {
+	switch sort := store.NoteSort(value); sort {
+	case "", store.NoteSortPosition, store.NoteSortUpdated, store.NoteSortCreated, store.NoteSortTitle, store.NoteSortRelevance:
+		return sort, nil
+	}
+	return "", fmt.Errorf("invalid sort (%s)", value)
+}
+
+func parseTagMatch(value string) (store.TagMatch, error) {
+	switch match := store.TagMatch(value); match {
+	case "", store.TagMatchAny, store.TagMatchAll:
+		return match, nil
+	}
+	return "", fmt.Errorf("invalid tag match (%s)", value)
+}
+
+func parseArchiveFilter(value string) (store.ArchiveFilter, error) {
+	switch filter := store.ArchiveFilter(value); filter {
+	case "", store.ArchiveActive, store.ArchiveArchived, store.ArchiveAll:
+		return filter, nil
+	}
+	return "", fmt.Errorf("invalid archived filter (%s)", value)
+}
+
+// ownedNote loads the note in the id URL parameter and checks that it
+// belongs to the current user, writing the error response itself when it
+// cannot.
+func (s *Server) ownedNote(w http.ResponseWriter, r *http.Request) (*models.Note, bool) {
 	userID := r.Context().Value(UserIDKey).(uuid.UUID)
-	idParam := chi.URLParam(r, "id")
-	id, err := uuid.Parse(idParam)
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
-		return
+		return nil, false
 	}
 
 	note, err := s.store.Notes.GetByID(r.Context(), id)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
-		return
+		return nil, false
 	}
 	if note == nil {
 		s.errorJSON(w, errors.New("note not found"), http.StatusNotFound)
-		return
+		return nil, false
 	}
 
 	if note.UserID != userID {
 		s.errorJSON(w, errors.New("forbidden"), http.StatusForbidden)
-		return
+		return nil, false
 	}
 
-	var input models.Note
-	var tagNames []string
+	return note, true
+}
 
-	contentType := r.Header.Get("Content-Type")
-	if strings.Contains(contentType, "application/json") {
-		if err := s.readJSON(w, r, &input); err != nil {
-			s.errorJSON(w, err, http.StatusBadRequest)
-			return
-		}
-	} else {
-		if err := r.ParseForm(); err != nil {
-			s.errorJSON(w, err, http.StatusBadRequest)
-			return
-		}
-		input.Title = r.FormValue("title")
-		input.Content = r.FormValue("content")
+// noteETag is the entity tag of the note's current version.
+func noteETag(note *models.Note) string {
+	return strconv.Quote(strconv.Itoa(note.Version))
+}
 
-		tagsJSON := r.FormValue("tags")
-		if tagsJSON != "" && tagsJSON != "[]" {
-			if err := json.Unmarshal([]byte(tagsJSON), &tagNames); err != nil {
-				s.errorJSON(w, err, http.StatusBadRequest)
-				return
-			}
+// matchesETag reports whether an If-Match header lists etag or is "*".
+func matchesETag(ifMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || candidate == etag {
+			return true
 		}
 	}
+	return false
+}
 
-	input.ID = id
-	if err := s.store.Notes.Update(r.Context(), &input); err != nil {
+// noteConflict answers an update based on a stale version with 412 and the
+// current copy of the note. HTMX requests get the conflict dialog, which the
+// layout lets through into the edit modal.
+func (s *Server) noteConflict(w http.ResponseWriter, r *http.Request, current *models.Note) {
+	tags, err := s.store.Notes.GetTags(r.Context(), current.ID)
+	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
+	current.Tags = tags
+
+	w.Header().Set("ETag", noteETag(current))
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("HX-Retarget", "#note-conflict")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		w.WriteHeader(http.StatusPreconditionFailed)
+		s.renderBlock(w, r, "note_conflict", map[string]any{
+			"Note": current,
+		})
+		return
+	}
 
-	if err := s.store.Notes.ClearTags(r.Context(), id); err != nil {
+	s.writeJSON(w, http.StatusPreconditionFailed, current)
+}
+
+// currentNoteConflict reloads the note after the store rejected a stale
+// update and reports the conflict with it.
+func (s *Server) currentNoteConflict(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	current, err := s.store.Notes.GetByID(r.Context(), id)
+	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
+	if current == nil {
+		s.errorJSON(w, errors.New("note not found"), http.StatusNotFound)
+		return
+	}
 
-	if len(tagNames) > 0 {
-		createdTags, err := s.store.Tags.FindOrCreate(r.Context(), note.UserID, tagNames)
-		if err != nil {
-			s.errorJSON(w, err, http.StatusInternalServerError)
-			return
-		}
+	s.noteConflict(w, r, current)
+}
 
-		var tagIDs []uuid.UUID
-		for _, tag := range createdTags {
-			tagIDs = append(tagIDs, tag.ID)
-		}
+func (s *Server) archiveNote(w http.ResponseWriter, r *http.Request) {
+	s.setNoteArchived(w, r, true)
+}
 
-		if err := s.store.Notes.AttachTags(r.Context(), id, tagIDs); err != nil {
-			s.errorJSON(w, err, http.StatusInternalServerError)
-			return
+func (s *Server) unarchiveNote(w http.ResponseWriter, r *http.Request) {
+	s.setNoteArchived(w, r, false)
+}
+
+func (s *Server) setNoteArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.Notes.SetArchived(r.Context(), note.ID, archived); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if r.Header.Get("HX-Request") != "" {
+		trigger := "note-unarchived"
+		if archived {
+			trigger = "note-archived"
 		}
+		s.removedCard(w, r, trigger)
+		return
+	}
+
+	note.Archived = archived
+	tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	note.Tags = tags
+
+	s.writeJSON(w, http.StatusOK, note)
+}
+
+func (s *Server) pinNote(w http.ResponseWriter, r *http.Request) {
+	s.setNotePinned(w, r, true)
+}
+
+func (s *Server) unpinNote(w http.ResponseWriter, r *http.Request) {
+	s.setNotePinned(w, r, false)
+}
+
+func (s *Server) setNotePinned(w http.ResponseWriter, r *http.Request, pinned bool) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.Notes.SetPinned(r.Context(), note.ID, pinned); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
 	}
 
-	updatedNote, err := s.store.Notes.GetByID(r.Context(), id)
+	note, err := s.store.Notes.GetByID(r.Context(), note.ID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	tags, err := s.store.Notes.GetTags(r.Context(), updatedNote.ID)
+	tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
-	updatedNote.Tags = tags
+	note.Tags = tags
 
 	if r.Header.Get("HX-Request") != "" {
+		// The note changes place, so the dashboard reloads its grid.
 		w.Header().Set("Content-Type", "text/html")
-		w.Header().Set("HX-Trigger", "note-updated")
-		data := map[string]any{
-			"Title":     updatedNote.Title,
-			"Content":   updatedNote.Content,
-			"ID":        updatedNote.ID,
-			"UpdatedAt": updatedNote.UpdatedAt,
-			"Tags":      updatedNote.Tags,
+		w.Header().Set("HX-Trigger", "notes-reordered")
+		s.renderBlock(w, r, "note-card", s.noteCardData(r, note))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, note)
+}
+
+func (s *Server) reorderNotes(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	var input struct {
+		IDs []uuid.UUID `json:"ids" validate:"required,min=1,max=1000,unique"`
+	}
+	if err := s.readJSON(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if err := s.validateStruct(input); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, s.formatValidationErrors(err))
+		return
+	}
+
+	if err := s.store.Notes.Reorder(r.Context(), userID, input.IDs); err != nil {
+		if errors.Is(err, store.ErrNoteNotFound) {
+			s.errorJSON(w, err, http.StatusNotFound)
+			return
 		}
-		s.renderBlock(w, r, "note-card", data)
+		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, updatedNote)
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (s *Server) deleteNote(w http.ResponseWriter, r *http.Request) {
+// removedCard answers requests that take a note out of the current view.
+// htmx does not swap 204 responses, so it gets an empty 200 instead.
+func (s *Server) removedCard(w http.ResponseWriter, r *http.Request, trigger string) {
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("HX-Trigger", trigger)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) getTrash(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserIDKey).(uuid.UUID)
-	idParam := chi.URLParam(r, "id")
-	id, err := uuid.Parse(idParam)
+	page, _ := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
+	if limit < 1 {
+		limit = 10
+	}
+
+	notes, count, err := s.store.Notes.GetTrash(r.Context(), userID, page, limit)
 	if err != nil {
-		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	note, err := s.store.Notes.GetByID(r.Context(), id)
+	if notes == nil {
+		notes = []models.Note{}
+	}
+
+	for i := range notes {
+		noteTags, err := s.store.Notes.GetTags(r.Context(), notes[i].ID)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		notes[i].Tags = noteTags
+	}
+
+	s.writeJSON(w, http.StatusOK, models.PaginatedNotesResponse{
+		Data: notes,
+		Meta: models.PaginationMetadata{
+			Page:       page,
+			Limit:      limit,
+			Count:      count,
+			TotalPages: (count + limit - 1) / limit,
+		},
+	})
+}
+
+// trashedNote loads a note from the trash of the current user, writing the
+// error response itself when it cannot.
+func (s *Server) trashedNote(w http.ResponseWriter, r *http.Request) (*models.Note, bool) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	note, err := s.store.Notes.GetDeletedByID(r.Context(), id)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
-		return
+		return nil, false
 	}
 	if note == nil {
-		s.errorJSON(w, errors.New("note not found"), http.StatusNotFound)
-		return
+		s.errorJSON(w, errors.New("note not found in trash"), http.StatusNotFound)
+		return nil, false
 	}
 
 	if note.UserID != userID {
 		s.errorJSON(w, errors.New("forbidden"), http.StatusForbidden)
+		return nil, false
+	}
+
+	return note, true
+}
+
+func (s *Server) restoreNote(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.trashedNote(w, r)
+	if !ok {
 		return
 	}
 
-	if err := s.store.Notes.Delete(r.Context(), id); err != nil {
+	if err := s.store.Notes.Restore(r.Context(), note.ID); err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	w.WriteHeader(http.StatusNoContent)
+	if r.Header.Get("HX-Request") != "" {
+		s.removedCard(w, r, "note-restored")
+		return
+	}
+
+	restored, err := s.store.Notes.GetByID(r.Context(), note.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := s.store.Notes.GetTags(r.Context(), restored.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	restored.Tags = tags
+
+	s.writeJSON(w, http.StatusOK, restored)
+}
+
+func (s *Server) purgeNote(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.trashedNote(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.Notes.Purge(r.Context(), note.ID); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.removedCard(w, r, "note-purged")
 }
 
 func (s *Server) getNoteTags(w http.ResponseWriter, r *http.Request) {
-	noteIDParam := chi.URLParam(r, "id")
-	noteID, err := uuid.Parse(noteIDParam)
-	if err != nil {
-		s.errorJSON(w, errors.New("invalid note id"), http.StatusBadRequest)
+	note, ok := s.ownedNote(w, r)
+	if !ok {
 		return
 	}
 
-	tags, err := s.store.Notes.GetTags(r.Context(), noteID)
+	tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
@@ -385,10 +888,8 @@ func (s *Server) getNoteTags(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) attachNoteTags(w http.ResponseWriter, r *http.Request) {
-	noteIDParam := chi.URLParam(r, "id")
-	noteID, err := uuid.Parse(noteIDParam)
-	if err != nil {
-		s.errorJSON(w, errors.New("invalid note id"), http.StatusBadRequest)
+	note, ok := s.ownedNote(w, r)
+	if !ok {
 		return
 	}
 
@@ -410,22 +911,15 @@ func (s *Server) attachNoteTags(w http.ResponseWriter, r *http.Request) {
 		newTagIDs = append(newTagIDs, id)
 	}
 
-	existingTags, err := s.store.Notes.GetTags(r.Context(), noteID)
+	existingTags, err := s.store.Notes.GetTags(r.Context(), note.ID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
 	for _, existingTag := range existingTags {
-		found := false
-		for _, newTagID := range newTagIDs {
-			if existingTag.ID == newTagID {
-				found = true
-				break
-			}
-		}
-		if !found {
-			if err := s.store.Notes.DetachTag(r.Context(), noteID, existingTag.ID); err != nil {
+		if !slices.Contains(newTagIDs, existingTag.ID) {
+			if err := s.store.Notes.DetachTag(r.Context(), note.ID, existingTag.ID); err != nil {
 				s.errorJSON(w, err, http.StatusInternalServerError)
 				return
 			}
@@ -433,91 +927,69 @@ func (s *Server) attachNoteTags(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if len(newTagIDs) > 0 {
-		if err := s.store.Notes.AttachTags(r.Context(), noteID, newTagIDs); err != nil {
+		if err := s.store.Notes.AttachTags(r.Context(), note.ID, newTagIDs); err != nil {
 			s.errorJSON(w, err, http.StatusInternalServerError)
 			return
 		}
 	}
 
-	note, err := s.store.Notes.GetByID(r.Context(), noteID)
-	if err != nil {
-		s.errorJSON(w, err, http.StatusInternalServerError)
-		return
-	}
-
-	tags, err := s.store.Notes.GetTags(r.Context(), noteID)
-	if err != nil {
-		s.errorJSON(w, err, http.StatusInternalServerError)
-		return
-	}
-	note.Tags = tags
-
-	s.writeJSON(w, http.StatusOK, note)
+	s.writeTaggedNote(w, r, note.ID)
 }
 
 func (s *Server) attachNoteTag(w http.ResponseWriter, r *http.Request) {
-	noteIDParam := chi.URLParam(r, "id")
-	noteID, err := uuid.Parse(noteIDParam)
-	if err != nil {
-		s.errorJSON(w, errors.New("invalid note id"), http.StatusBadRequest)
+	note, ok := s.ownedNote(w, r)
+	if !ok {
 		return
 	}
 
-	tagIDParam := chi.URLParam(r, "tagId")
-	tagID, err := uuid.Parse(tagIDParam)
+	tagID, err := uuid.Parse(chi.URLParam(r, "tagId"))
 	if err != nil {
 		s.errorJSON(w, errors.New("invalid tag id"), http.StatusBadRequest)
 		return
 	}
 
-	if err := s.store.Notes.AttachTags(r.Context(), noteID, []uuid.UUID{tagID}); err != nil {
+	if err := s.store.Notes.AttachTags(r.Context(), note.ID, []uuid.UUID{tagID}); err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	note, err := s.store.Notes.GetByID(r.Context(), noteID)
-	if err != nil {
-		s.errorJSON(w, err, http.StatusInternalServerError)
-		return
-	}
-
-	tags, err := s.store.Notes.GetTags(r.Context(), noteID)
-	if err != nil {
-		s.errorJSON(w, err, http.StatusInternalServerError)
-		return
-	}
-	note.Tags = tags
-
-	s.writeJSON(w, http.StatusOK, note)
+	s.writeTaggedNote(w, r, note.ID)
 }
 
 func (s *Server) detachNoteTag(w http.ResponseWriter, r *http.Request) {
-	noteIDParam := chi.URLParam(r, "id")
-	noteID, err := uuid.Parse(noteIDParam)
-	if err != nil {
-		s.errorJSON(w, errors.New("invalid note id"), http.StatusBadRequest)
+	note, ok := s.ownedNote(w, r)
+	if !ok {
 		return
 	}
 
-	tagIDParam := chi.URLParam(r, "tagId")
-	tagID, err := uuid.Parse(tagIDParam)
+	tagID, err := uuid.Parse(chi.URLParam(r, "tagId"))
 	if err != nil {
 		s.errorJSON(w, errors.New("invalid tag id"), http.StatusBadRequest)
 		return
 	}
 
-	if err := s.store.Notes.DetachTag(r.Context(), noteID, tagID); err != nil {
+	if err := s.store.Notes.DetachTag(r.Context(), note.ID, tagID); err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	note, err := s.store.Notes.GetByID(r.Context(), noteID)
+	s.writeTaggedNote(w, r, note.ID)
+}
+
+// writeTaggedNote answers with the note and its tags as they are after a
+// change, or 404 when the note went to the trash in the meantime.
+func (s *Server) writeTaggedNote(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	note, err := s.store.Notes.GetByID(r.Context(), id)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
+	if note == nil {
+		s.errorJSON(w, errors.New("note not found"), http.StatusNotFound)
+		return
+	}
 
-	tags, err := s.store.Notes.GetTags(r.Context(), noteID)
+	tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
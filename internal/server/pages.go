@@ -2,16 +2,20 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
 	"net/http"
 	"path/filepath"
-	"strconv"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/search"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/tagcolor"
 )
 
 func (s *Server) render(w http.ResponseWriter, r *http.Request, page string, data map[string]any) {
@@ -26,6 +30,12 @@ func (s *Server) render(w http.ResponseWriter, r *http.Request, page string, dat
 			return template.HTML(s)
 		},
 		"t": t,
+		"percent": func(part, total int) int {
+			if total == 0 {
+				return 0
+			}
+			return part * 100 / total
+		},
 		"add": func(a, b int64) int64 {
 			return a + b
 		},
@@ -49,6 +59,7 @@ func (s *Server) render(w http.ResponseWriter, r *http.Request, page string, dat
 			b, _ := json.Marshal(v)
 			return template.JS(b)
 		},
+		"tagTextColor": tagcolor.TextColor,
 	}
 
 	partials, err := filepath.Glob("web/templates/partials/*.html")
@@ -129,10 +140,17 @@ func (s *Server) renderBlock(w http.ResponseWriter, r *http.Request, block strin
 			return template.HTML(s)
 		},
 		"t": t,
+		"percent": func(part, total int) int {
+			if total == 0 {
+				return 0
+			}
+			return part * 100 / total
+		},
 		"toJSON": func(v any) template.JS {
 			b, _ := json.Marshal(v)
 			return template.JS(b)
 		},
+		"tagTextColor": tagcolor.TextColor,
 	}
 
 	partials, err := filepath.Glob("web/templates/partials/*.html")
@@ -203,22 +221,105 @@ func (s *Server) registerPage(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *Server) dashboard(w http.ResponseWriter, r *http.Request) {
-	userID := r.Context().Value(UserIDKey).(uuid.UUID)
-	page, _ := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
-	if page < 1 {
-		page = 1
+// sameTags reports whether a and b hold the same tag paths in any order.
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, tag := range b {
+		if !slices.Contains(a, store.TagPath(tag)) {
+			return false
+		}
 	}
-	limit, _ := strconv.ParseInt(r.URL.Query().Get("limit"), 10, 64)
-	if limit < 1 {
-		limit = 10
+	return true
+}
+
+// dashboardTagFilters flattens the tag tree for the sidebar, each tag right
+// after its parent and indented by depth, marking those in selected.
+func dashboardTagFilters(nodes []models.TagNode, selected []string, depth int) []map[string]any {
+	var filters []map[string]any
+	for _, node := range nodes {
+		label := node.Name
+		if i := strings.LastIndex(label, "/"); i >= 0 {
+			label = label[i+1:]
+		}
+
+		filters = append(filters, map[string]any{
+			"Name":     node.Name,
+			"Label":    label,
+			"Color":    node.Color,
+			"Depth":    depth,
+			"Count":    node.TotalCount,
+			"Selected": slices.Contains(selected, node.Name),
+		})
+		filters = append(filters, dashboardTagFilters(node.Children, selected, depth+1)...)
 	}
+	return filters
+}
 
-	search := r.URL.Query().Get("search")
+func (s *Server) dashboard(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+	page := readPage(r, 10)
+
+	searchText := r.URL.Query().Get("search")
 	tags := r.URL.Query()["tags"]
 
-	notes, count, err := s.store.Notes.GetAll(r.Context(), userID, int64(page), int64(limit), search, tags)
-	if err != nil {
+	view := r.URL.Query().Get("view")
+
+	// An unknown sort or match is shown with the default rather than
+	// failing the whole page.
+	sort, _ := parseNoteSort(r.URL.Query().Get("sort"))
+	match, _ := parseTagMatch(r.URL.Query().Get("match"))
+	if match == "" {
+		match = store.TagMatchAny
+	}
+
+	var notes []models.Note
+	var meta models.PaginationMetadata
+	var err error
+	if view == "trash" {
+		if page.Number < 1 {
+			page.Number = 1
+		}
+		var count int64
+		notes, count, err = s.store.Notes.GetTrash(r.Context(), userID, page.Number, page.Limit)
+		meta = models.PaginationMetadata{
+			Page:       page.Number,
+			Limit:      page.Limit,
+			Count:      count,
+			TotalPages: (count + page.Limit - 1) / page.Limit,
+		}
+	} else {
+		var archived store.ArchiveFilter
+		if view == "archive" {
+			archived = store.ArchiveArchived
+		} else {
+			view = "notes"
+		}
+
+		// The notes views scroll through cursor pages instead of numbered ones.
+		page = store.Page{Limit: page.Limit, Cursor: r.URL.Query().Get("cursor"), Keyset: true}
+
+		var query *search.Query
+		query, err = search.Parse(searchText)
+		if err == nil {
+			notes, meta, err = s.store.Notes.GetAll(r.Context(), userID, page, store.NoteFilter{
+				Query:    query,
+				Tags:     tags,
+				TagMatch: match,
+				Locale:   r.Context().Value(localeKey).(string),
+				Archived: archived,
+				Sort:     sort,
+			})
+		}
+	}
+
+	searchError, isSearchError := s.searchErrorMessage(r, err)
+	if err != nil && !isSearchError {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			s.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
@@ -236,14 +337,102 @@ func (s *Server) dashboard(w http.ResponseWriter, r *http.Request) {
 		notes[i].Tags = noteTags
 	}
 
+	cards := make([]map[string]any, 0, len(notes))
+	for i := range notes {
+		cards = append(cards, s.noteCardData(r, &notes[i]))
+	}
+
+	var tagFilters []map[string]any
+	if view != "trash" {
+		tree, err := s.store.Tags.GetTree(r.Context(), userID)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		tagFilters = dashboardTagFilters(tree, tags, 0)
+	}
+
+	user, err := s.store.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	templates, err := s.store.Templates.GetAll(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	var dailyTemplateID uuid.UUID
+	if user != nil && user.DailyTemplateID != nil {
+		dailyTemplateID = *user.DailyTemplateID
+	}
+	loc, err := s.userLocation(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// Links from the calendar feed open a note over the dashboard.
+	var openNote string
+	if id, err := uuid.Parse(r.URL.Query().Get("note")); err == nil {
+		openNote = id.String()
+	}
+
+	savedSearches, err := s.store.SavedSearches.GetAll(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if err := s.countSavedSearches(r.Context(), userID, savedSearches); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	savedSearchLinks := make([]map[string]any, 0, len(savedSearches))
+	for i := range savedSearches {
+		saved := &savedSearches[i]
+		savedSearchLinks = append(savedSearchLinks, map[string]any{
+			"ID":    saved.ID,
+			"Name":  saved.Name,
+			"Count": saved.NoteCount,
+			"URL":   savedSearchURL(r.Context().Value(localeKey).(string), saved),
+			"Active": view == "notes" && saved.Search == searchText && saved.TagMatch == string(match) &&
+				saved.Sort == string(sort) && sameTags(saved.Tags, tags),
+		})
+	}
+
+	// Refreshing the grid starts over from the first page; the next page
+	// continues from the cursor.
+	params := r.URL.Query()
+	params.Del("cursor")
+	currentURL := r.URL.Path + "?" + params.Encode()
+	nextURL := ""
+	if meta.NextCursor != "" {
+		params.Set("cursor", meta.NextCursor)
+		nextURL = r.URL.Path + "?" + params.Encode()
+	}
+
 	s.render(w, r, "dashboard.html", map[string]any{
-		"Title": "dashboard.title",
-		"Notes": notes,
-		"Meta": models.PaginationMetadata{
-			Page:       (page),
-			Limit:      (limit),
-			Count:      count,
-			TotalPages: (count + limit - 1) / limit,
-		},
+		"Title":           "dashboard.title",
+		"Notes":           notes,
+		"Cards":           cards,
+		"Search":          searchText,
+		"SearchError":     searchError,
+		"View":            view,
+		"Sort":            meta.Sort,
+		"TagFilters":      tagFilters,
+		"TagMatch":        string(match),
+		"SavedSearches":   savedSearchLinks,
+		"Templates":       templates,
+		"DailyTemplateID": dailyTemplateID,
+		"Today":           today(loc).Format(dailyDateLayout),
+		"CalendarFeed":    user != nil && user.CalendarTokenHash != nil,
+		"OpenNote":        openNote,
+		"CurrentURL":      currentURL,
+		"NextURL":         nextURL,
+		"Sortable":        view != "trash" && searchText == "" && meta.Sort == string(store.NoteSortPosition),
+		"TrashNotice": s.i18n.Translate(r.Context().Value(localeKey).(string), "dashboard.trash_notice", map[string]any{
+			"Days": int(s.cfg.TrashRetention.Hours() / 24),
+		}),
+		"Meta": meta,
 	})
 }
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/diff"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+)
+
+func (s *Server) getNoteRevisions(w http.ResponseWriter, r *http.Request) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	revisions, err := s.store.Notes.GetRevisions(r.Context(), note.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if revisions == nil {
+		revisions = []models.NoteRevision{}
+	}
+
+	s.writeJSON(w, http.StatusOK, revisions)
+}
+
+// noteRevision loads the revision in the rev URL parameter of a note owned
+// by the current user, writing the error response itself when it cannot.
+func (s *Server) noteRevision(w http.ResponseWriter, r *http.Request) (*models.Note, *models.NoteRevision, bool) {
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return nil, nil, false
+	}
+
+	number, err := strconv.Atoi(chi.URLParam(r, "rev"))
+	if err != nil || number < 1 {
+		s.errorJSON(w, errors.New("invalid revision"), http.StatusBadRequest)
+		return nil, nil, false
+	}
+
+	revision, err := s.store.Notes.GetRevision(r.Context(), note.ID, number)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return nil, nil, false
+	}
+	if revision == nil {
+		s.errorJSON(w, errors.New("revision not found"), http.StatusNotFound)
+		return nil, nil, false
+	}
+
+	return note, revision, true
+}
+
+func (s *Server) getNoteRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	note, revision, ok := s.noteRevision(w, r)
+	if !ok {
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"revision": revision.Revision,
+		"title": map[string]string{
+			"revision": revision.Title,
+			"current":  note.Title,
+		},
+		"content": diff.Lines(revision.Content, note.Content),
+	})
+}
+
+func (s *Server) restoreNoteRevision(w http.ResponseWriter, r *http.Request) {
+	note, revision, ok := s.noteRevision(w, r)
+	if !ok {
+		return
+	}
+
+	note.Title = revision.Title
+	note.Content = revision.Content
+	if err := s.store.Notes.Update(r.Context(), note); err != nil {
+		if errors.Is(err, store.ErrVersionConflict) {
+			s.currentNoteConflict(w, r, note.ID)
+			return
+		}
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	note.Tags = tags
+
+	if r.Header.Get("HX-Request") != "" {
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("HX-Trigger", "note-updated")
+		s.renderBlock(w, r, "note-card", s.noteCardData(r, note))
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, note)
+}
@@ -17,14 +17,14 @@ const localeKey contextKey = "locale"
 func (s *Server) routes() http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Logger)
+	r.Use(requestLogger)
 	r.Use(middleware.Recoverer)
 	r.Use(s.securityHeaders)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   s.cfg.AllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", "If-Match"},
+		ExposedHeaders:   []string{"Link", "ETag"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
@@ -49,8 +49,11 @@ func (s *Server) routes() http.Handler {
 		r.Group(func(r chi.Router) {
 			r.Use(s.AuthMiddleware)
 			r.Get("/dashboard", s.dashboard)
+			r.Get("/dashboard/graph", s.graphPage)
 		})
 
+		r.Get("/calendar/{token}.ics", s.getCalendarFeed)
+
 		r.Route("/auth", func(r chi.Router) {
 			r.Post("/register", s.register)
 			r.Post("/login", s.login)
@@ -60,17 +63,44 @@ func (s *Server) routes() http.Handler {
 		r.Route("/users", func(r chi.Router) {
 			r.Use(s.AuthMiddleware)
 			r.Get("/me", s.getMe)
+			r.Patch("/me", s.updateMe)
+			r.Post("/me/calendar-token", s.createCalendarToken)
+			r.Delete("/me/calendar-token", s.deleteCalendarToken)
 		})
 
 		r.Route("/notes", func(r chi.Router) {
 			r.Use(s.AuthMiddleware)
 			r.Get("/", s.getNotes)
 			r.Get("/new", s.createNotePage)
+			r.Get("/trash", s.getTrash)
+			r.Get("/links/dangling", s.getDanglingLinks)
+			r.Get("/graph", s.getNoteGraph)
+			r.Get("/daily", s.getDailyDates)
+			r.Get("/daily/{date}", s.getDailyNote)
 			r.Post("/", s.createNote)
+			r.Post("/reorder", s.reorderNotes)
 			r.Get("/{id}", s.getNote)
 			r.Get("/{id}/edit", s.editNotePage)
 			r.Patch("/{id}", s.updateNote)
 			r.Delete("/{id}", s.deleteNote)
+			r.Post("/{id}/archive", s.archiveNote)
+			r.Post("/{id}/unarchive", s.unarchiveNote)
+			r.Post("/{id}/move", s.moveNote)
+			r.Post("/{id}/pin", s.pinNote)
+			r.Post("/{id}/unpin", s.unpinNote)
+			r.Patch("/{id}/schedule", s.updateNoteSchedule)
+			r.Post("/{id}/restore", s.restoreNote)
+			r.Delete("/{id}/purge", s.purgeNote)
+
+			r.Get("/{id}/links", s.getNoteLinks)
+			r.Get("/{id}/backlinks", s.getNoteBacklinks)
+
+			r.Get("/{id}/checklist", s.getNoteChecklist)
+			r.Patch("/{id}/checklist/{index}", s.updateNoteChecklistItem)
+
+			r.Get("/{id}/revisions", s.getNoteRevisions)
+			r.Get("/{id}/revisions/{rev}/diff", s.getNoteRevisionDiff)
+			r.Post("/{id}/revisions/{rev}/restore", s.restoreNoteRevision)
 
 			r.Get("/{id}/tags", s.getNoteTags)
 			r.Patch("/{id}/tags", s.attachNoteTags)
@@ -78,14 +108,47 @@ func (s *Server) routes() http.Handler {
 			r.Delete("/{id}/tags/{tagId}", s.detachNoteTag)
 		})
 
+		r.Route("/notebooks", func(r chi.Router) {
+			r.Use(s.AuthMiddleware)
+			r.Get("/", s.getNotebooks)
+			r.Post("/", s.createNotebook)
+			r.Get("/{id}", s.getNotebook)
+			r.Patch("/{id}", s.updateNotebook)
+			r.Delete("/{id}", s.deleteNotebook)
+			r.Post("/{id}/move", s.moveNotebook)
+		})
+
+		r.Route("/saved-searches", func(r chi.Router) {
+			r.Use(s.AuthMiddleware)
+			r.Get("/", s.getSavedSearches)
+			r.Post("/", s.createSavedSearch)
+			r.Get("/{id}", s.getSavedSearch)
+			r.Patch("/{id}", s.updateSavedSearch)
+			r.Delete("/{id}", s.deleteSavedSearch)
+			r.Get("/{id}/notes", s.getSavedSearchNotes)
+		})
+
+		r.Route("/templates", func(r chi.Router) {
+			r.Use(s.AuthMiddleware)
+			r.Get("/", s.getNoteTemplates)
+			r.Post("/", s.createNoteTemplate)
+			r.Get("/{id}", s.getNoteTemplate)
+			r.Patch("/{id}", s.updateNoteTemplate)
+			r.Delete("/{id}", s.deleteNoteTemplate)
+			r.Get("/{id}/render", s.renderNoteTemplateHandler)
+		})
+
 		r.Route("/tags", func(r chi.Router) {
 			r.Use(s.AuthMiddleware)
 			r.Get("/", s.getTags)
 			r.Post("/", s.createTag)
 			r.Post("/find-or-create", s.findTagsOrCreate)
+			r.Get("/manage", s.manageTagsPage)
+			r.Delete("/unused", s.deleteUnusedTags)
 			r.Get("/{id}", s.getTag)
 			r.Patch("/{id}", s.updateTag)
 			r.Delete("/{id}", s.deleteTag)
+			r.Post("/{id}/merge", s.mergeTag)
 		})
 	})
 
@@ -0,0 +1,351 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/search"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+)
+
+var errSavedSearchNotFound = errors.New("saved search not found")
+
+type savedSearchInput struct {
+	Name   string   `json:"name" validate:"required,max=100"`
+	Search string   `json:"search" validate:"max=1000"`
+	Tags   []string `json:"tags" validate:"max=50"`
+	Match  string   `json:"match"`
+	Sort   string   `json:"sort"`
+}
+
+// savedSearchFilter returns the filter the dashboard would build from the
+// same search, tags, match and sort parameters.
+func savedSearchFilter(saved *models.SavedSearch, locale string) (store.NoteFilter, error) {
+	query, err := search.Parse(saved.Search)
+	if err != nil {
+		return store.NoteFilter{}, err
+	}
+	return store.NoteFilter{
+		Query:    query,
+		Tags:     saved.Tags,
+		TagMatch: store.TagMatch(saved.TagMatch),
+		Locale:   locale,
+		Sort:     store.NoteSort(saved.Sort),
+	}, nil
+}
+
+// savedSearchURL returns the dashboard address that runs the saved search.
+func savedSearchURL(locale string, saved *models.SavedSearch) string {
+	params := url.Values{}
+	if saved.Search != "" {
+		params.Set("search", saved.Search)
+	}
+	for _, tag := range saved.Tags {
+		params.Add("tags", tag)
+	}
+	if saved.TagMatch != string(store.TagMatchAny) {
+		params.Set("match", saved.TagMatch)
+	}
+	if saved.Sort != "" {
+		params.Set("sort", saved.Sort)
+	}
+
+	address := "/" + locale + "/dashboard"
+	if len(params) > 0 {
+		address += "?" + params.Encode()
+	}
+	return address
+}
+
+// countSavedSearches fills in the number of notes each saved search
+// currently finds. Searches whose query no longer parses are left without
+// a count.
+func (s *Server) countSavedSearches(ctx context.Context, userID uuid.UUID, searches []models.SavedSearch) error {
+	locale := ctx.Value(localeKey).(string)
+	for i := range searches {
+		filter, err := savedSearchFilter(&searches[i], locale)
+		if err != nil {
+			continue
+		}
+
+		_, meta, err := s.store.Notes.GetAll(ctx, userID, store.Page{Number: 1, Limit: 1}, filter)
+		if err != nil {
+			var searchErr *search.Error
+			if errors.As(err, &searchErr) {
+				continue
+			}
+			return err
+		}
+		searches[i].NoteCount = &meta.Count
+	}
+	return nil
+}
+
+// applySavedSearchInput validates input and copies it onto saved, writing
+// the error response itself when it cannot.
+func (s *Server) applySavedSearchInput(w http.ResponseWriter, r *http.Request, saved *models.SavedSearch, input savedSearchInput) bool {
+	if err := s.validateStruct(input); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, s.formatValidationErrors(err))
+		return false
+	}
+
+	match, err := parseTagMatch(input.Match)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return false
+	}
+	if match == "" {
+		match = store.TagMatchAny
+	}
+	sort, err := parseNoteSort(input.Sort)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return false
+	}
+	if _, err := search.Parse(input.Search); err != nil {
+		s.searchErrorJSON(w, r, err)
+		return false
+	}
+
+	saved.Name = input.Name
+	saved.Search = input.Search
+	saved.Tags = tagPaths(input.Tags)
+	saved.TagMatch = string(match)
+	saved.Sort = string(sort)
+	return true
+}
+
+func (s *Server) getSavedSearches(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	searches, err := s.store.SavedSearches.GetAll(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if err := s.countSavedSearches(r.Context(), userID, searches); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"data":  searches,
+		"count": len(searches),
+	})
+}
+
+func (s *Server) getSavedSearch(w http.ResponseWriter, r *http.Request) {
+	saved, ok := s.ownedSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	searches := []models.SavedSearch{*saved}
+	if err := s.countSavedSearches(r.Context(), saved.UserID, searches); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, searches[0])
+}
+
+func (s *Server) createSavedSearch(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	var input savedSearchInput
+	if err := s.readJSON(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	saved := models.SavedSearch{UserID: userID}
+	if !s.applySavedSearchInput(w, r, &saved, input) {
+		return
+	}
+
+	if err := s.store.SavedSearches.Create(r.Context(), &saved); err != nil {
+		if errors.Is(err, store.ErrSavedSearchExists) {
+			s.errorJSON(w, err, http.StatusConflict)
+			return
+		}
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, saved)
+}
+
+// updateSavedSearch applies a JSON merge patch: the fields given in the body
+// change and the rest are kept.
+func (s *Server) updateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	saved, ok := s.ownedSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	input := savedSearchInput{
+		Name:   saved.Name,
+		Search: saved.Search,
+		Tags:   saved.Tags,
+		Match:  saved.TagMatch,
+		Sort:   saved.Sort,
+	}
+	if err := s.readSavedSearchPatch(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !s.applySavedSearchInput(w, r, saved, input) {
+		return
+	}
+
+	if err := s.store.SavedSearches.Update(r.Context(), saved); err != nil {
+		if errors.Is(err, store.ErrSavedSearchExists) {
+			s.errorJSON(w, err, http.StatusConflict)
+			return
+		}
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, saved)
+}
+
+// readSavedSearchPatch reads a JSON merge patch onto input. The name cannot
+// be removed; a null search or sort clears it, null tags remove them all
+// and a null match goes back to any.
+func (s *Server) readSavedSearchPatch(w http.ResponseWriter, r *http.Request, input *savedSearchInput) error {
+	members, err := s.readMergePatch(w, r, "name", "search", "tags", "match", "sort")
+	if err != nil {
+		return err
+	}
+
+	name, err := patchString(members, "name")
+	if err != nil {
+		return err
+	}
+	if name != nil {
+		input.Name = *name
+	}
+
+	clearable := []struct {
+		key   string
+		field *string
+	}{
+		{"search", &input.Search},
+		{"match", &input.Match},
+		{"sort", &input.Sort},
+	}
+	for _, member := range clearable {
+		raw, ok := members[member.key]
+		if !ok {
+			continue
+		}
+		*member.field = ""
+		if !isJSONNull(raw) {
+			if err := json.Unmarshal(raw, member.field); err != nil {
+				return fmt.Errorf("invalid %s: %w", member.key, err)
+			}
+		}
+	}
+
+	if raw, ok := members["tags"]; ok {
+		input.Tags = []string{}
+		if !isJSONNull(raw) {
+			if err := json.Unmarshal(raw, &input.Tags); err != nil {
+				return fmt.Errorf("invalid tags: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) deleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	saved, ok := s.ownedSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.SavedSearches.Delete(r.Context(), saved.ID); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getSavedSearchNotes runs the saved search, paginated like getNotes.
+func (s *Server) getSavedSearchNotes(w http.ResponseWriter, r *http.Request) {
+	saved, ok := s.ownedSavedSearch(w, r)
+	if !ok {
+		return
+	}
+
+	filter, err := savedSearchFilter(saved, r.Context().Value(localeKey).(string))
+	if err != nil {
+		s.searchErrorJSON(w, r, err)
+		return
+	}
+
+	notes, meta, err := s.store.Notes.GetAll(r.Context(), saved.UserID, readPage(r, 10), filter)
+	if err != nil {
+		if s.searchErrorJSON(w, r, err) {
+			return
+		}
+		if errors.Is(err, store.ErrInvalidCursor) {
+			s.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if notes == nil {
+		notes = []models.Note{}
+	}
+
+	for i := range notes {
+		noteTags, err := s.store.Notes.GetTags(r.Context(), notes[i].ID)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		notes[i].Tags = noteTags
+	}
+
+	s.writeJSON(w, http.StatusOK, models.PaginatedNotesResponse{
+		Data: notes,
+		Meta: meta,
+	})
+}
+
+// ownedSavedSearch loads the saved search in the id URL parameter and
+// checks that it belongs to the current user, writing the error response
+// itself when it cannot.
+func (s *Server) ownedSavedSearch(w http.ResponseWriter, r *http.Request) (*models.SavedSearch, bool) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	saved, err := s.store.SavedSearches.GetByID(r.Context(), id)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return nil, false
+	}
+	if saved == nil || saved.UserID != userID {
+		s.errorJSON(w, errSavedSearchNotFound, http.StatusNotFound)
+		return nil, false
+	}
+
+	return saved, true
+}
@@ -0,0 +1,100 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// scheduleLocalLayout is the form of datetime-local inputs, a wall clock
+// time in the timezone of the user.
+const scheduleLocalLayout = "2006-01-02T15:04"
+
+// patchScheduleTime decodes a merge patch member holding a due date or
+// reminder time. It reports false when the member was left out, and a nil
+// time when it removes the value. Times without an offset are read in loc.
+func patchScheduleTime(members map[string]json.RawMessage, key string, loc *time.Location) (*time.Time, bool, error) {
+	raw, ok := members[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if isJSONNull(raw) {
+		return nil, true, nil
+	}
+
+	var value string
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.ParseInLocation(scheduleLocalLayout, value, loc); err != nil {
+			return nil, false, fmt.Errorf("invalid %s, expected RFC 3339 or YYYY-MM-DDTHH:MM", key)
+		}
+	}
+	t = t.UTC()
+	return &t, true, nil
+}
+
+// updateNoteSchedule sets or clears the due date and reminder time of the
+// note with a JSON merge patch of dueAt and remindAt.
+func (s *Server) updateNoteSchedule(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+	note, ok := s.ownedNote(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := s.readMergePatch(w, r, "dueAt", "remindAt")
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	loc, err := s.userLocation(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	dueAt, ok, err := patchScheduleTime(members, "dueAt", loc)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if ok {
+		note.DueAt = dueAt
+	}
+	remindAt, ok, err := patchScheduleTime(members, "remindAt", loc)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if ok {
+		note.RemindAt = remindAt
+	}
+
+	if err := s.store.Notes.SetSchedule(r.Context(), note.ID, note.DueAt, note.RemindAt); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	note, err = s.store.Notes.GetByID(r.Context(), note.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	tags, err := s.store.Notes.GetTags(r.Context(), note.ID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	note.Tags = tags
+
+	s.writeJSON(w, http.StatusOK, note)
+}
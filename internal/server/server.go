@@ -9,6 +9,7 @@ import (
 
 	"github.com/manuelmtzv/mangocatnotes-api/internal/config"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/i18n"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/markdown"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/session"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
 )
@@ -19,16 +20,18 @@ type Server struct {
 	i18n         *i18n.Manager
 	store        *store.Storage
 	session      *session.SessionManager
+	markdown     *markdown.Renderer
 	AssetVersion string
 }
 
-func New(cfg *config.Config, logger *zap.SugaredLogger, store *store.Storage, session *session.SessionManager) *Server {
+func New(cfg *config.Config, logger *zap.SugaredLogger, store *store.Storage, session *session.SessionManager, markdown *markdown.Renderer) *Server {
 	return &Server{
 		cfg:          cfg,
 		logger:       logger,
 		i18n:         i18n.NewManager(),
 		store:        store,
 		session:      session,
+		markdown:     markdown,
 		AssetVersion: fmt.Sprintf("%d", time.Now().Unix()),
 	}
 }
@@ -4,21 +4,41 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"slices"
+	"strconv"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/tagcolor"
 )
 
+// allTags is the page read where every tag of the user is needed at once.
+var allTags = store.Page{Number: 1, Limit: 1000}
+
 func (s *Server) createTag(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserIDKey).(uuid.UUID)
-	var input models.Tag
+	var input struct {
+		Name  string `json:"name"`
+		Color string `json:"color" validate:"omitempty,tagcolor"`
+	}
 	if err := s.readJSON(w, r, &input); err != nil {
 		s.errorJSON(w, err, http.StatusBadRequest)
 		return
 	}
+	if err := s.validateStruct(input); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, s.formatValidationErrors(err))
+		return
+	}
 
-	existingTags, _, err := s.store.Tags.GetAll(r.Context(), userID, 1, 1000)
+	input.Name = store.TagPath(input.Name)
+	if input.Name == "" {
+		s.errorJSON(w, errors.New("tag name is required"), http.StatusBadRequest)
+		return
+	}
+
+	existingTags, _, err := s.store.Tags.GetAll(r.Context(), userID, allTags, store.TagFilter{})
 	if err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
@@ -31,20 +51,58 @@ func (s *Server) createTag(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	input.UserID = userID
-	if err := s.store.Tags.Create(r.Context(), &input); err != nil {
+	// An empty color is left for the store to pick from the palette.
+	tag := models.Tag{UserID: userID, Name: input.Name}
+	tag.Color, _ = tagcolor.Normalize(input.Color)
+	if err := s.store.Tags.Create(r.Context(), &tag); err != nil {
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	s.writeJSON(w, http.StatusCreated, input)
+	s.writeJSON(w, http.StatusCreated, tag)
 }
 
+// getTags lists the tags of the user, with their note count and last use
+// when usage=true, or with view=tree returns them nested under their
+// parents with note counts.
 func (s *Server) getTags(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserIDKey).(uuid.UUID)
 
-	tags, count, err := s.store.Tags.GetAll(r.Context(), userID, 1, 1000)
+	if r.URL.Query().Get("view") == "tree" {
+		tree, err := s.store.Tags.GetTree(r.Context(), userID)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		if tree == nil {
+			tree = []models.TagNode{}
+		}
+
+		s.writeJSON(w, http.StatusOK, map[string]any{
+			"data": tree,
+		})
+		return
+	}
+
+	sort, err := parseTagSort(r.URL.Query().Get("sort"))
 	if err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// Without a limit every tag is listed, as clients picking tags expect.
+	usage, _ := strconv.ParseBool(r.URL.Query().Get("usage"))
+
+	page := readPage(r, allTags.Limit)
+	tags, meta, err := s.store.Tags.GetAll(r.Context(), userID, page, store.TagFilter{
+		Sort:  sort,
+		Usage: usage,
+	})
+	if err != nil {
+		if errors.Is(err, store.ErrInvalidCursor) {
+			s.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
@@ -53,65 +111,237 @@ func (s *Server) getTags(w http.ResponseWriter, r *http.Request) {
 		tags = []models.Tag{}
 	}
 
-	s.writeJSON(w, http.StatusOK, map[string]any{
-		"data":  tags,
-		"count": count,
-	})
+	response := map[string]any{
+		"data": tags,
+		"meta": meta,
+	}
+	if !page.Keyset {
+		response["count"] = meta.Count
+	}
+	s.writeJSON(w, http.StatusOK, response)
+}
+
+func parseTagSort(value string) (store.TagSort, error) {
+	switch sort := store.TagSort(value); sort {
+	case "", store.TagSortName, store.TagSortCreated, store.TagSortUpdated, store.TagSortPopularity:
+		return sort, nil
+	}
+	return "", fmt.Errorf("invalid sort (%s)", value)
 }
 
 func (s *Server) getTag(w http.ResponseWriter, r *http.Request) {
-	idParam := chi.URLParam(r, "id")
-	id, err := uuid.Parse(idParam)
-	if err != nil {
-		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+	tag, ok := s.ownedTag(w, r)
+	if !ok {
 		return
 	}
 
-	tag, err := s.store.Tags.GetByID(r.Context(), id)
+	s.writeJSON(w, http.StatusOK, tag)
+}
+
+// updateTag renames or recolors a tag. Renaming onto the name of another
+// tag answers 409 with that tag unless merge=true is given, in which case
+// the tag is merged into it and any new color applies to the merged tag.
+func (s *Server) updateTag(w http.ResponseWriter, r *http.Request) {
+	tag, ok := s.ownedTag(w, r)
+	if !ok {
+		return
+	}
+
+	members, err := s.readMergePatch(w, r, "name", "color")
 	if err != nil {
-		s.errorJSON(w, err, http.StatusInternalServerError)
+		s.errorJSON(w, err, http.StatusBadRequest)
 		return
 	}
-	if tag == nil {
-		s.errorJSON(w, errors.New("tag not found"), http.StatusNotFound)
+
+	var patch store.TagPatch
+	if patch.Name, err = patchString(members, "name"); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if patch.Name != nil && store.TagPath(*patch.Name) == "" {
+		s.errorJSON(w, errors.New("tag name is required"), http.StatusBadRequest)
 		return
 	}
+	if patch.Color, err = patchString(members, "color"); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if patch.Color != nil {
+		color := struct {
+			Color string `json:"color" validate:"tagcolor"`
+		}{*patch.Color}
+		if err := s.validateStruct(color); err != nil {
+			s.writeJSON(w, http.StatusBadRequest, s.formatValidationErrors(err))
+			return
+		}
+		*patch.Color, _ = tagcolor.Normalize(*patch.Color)
+	}
+
+	if patch.Name != nil {
+		existing, err := s.store.Tags.GetByName(r.Context(), tag.UserID, *patch.Name)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+
+		if existing != nil && existing.ID != tag.ID {
+			if merge, _ := strconv.ParseBool(r.URL.Query().Get("merge")); !merge {
+				s.tagExists(w, r, tag, existing)
+				return
+			}
+
+			if err := s.store.Tags.Merge(r.Context(), tag, existing); err != nil {
+				if errors.Is(err, store.ErrTagCycle) {
+					s.errorJSON(w, err, http.StatusBadRequest)
+					return
+				}
+				s.errorJSON(w, err, http.StatusInternalServerError)
+				return
+			}
+			tag = existing
+			patch.Name = nil
+		}
+	}
+
+	if patch != (store.TagPatch{}) {
+		if err := s.store.Tags.Patch(r.Context(), tag, patch); err != nil {
+			switch {
+			case errors.Is(err, store.ErrTagCycle):
+				s.errorJSON(w, err, http.StatusBadRequest)
+			case errors.Is(err, store.ErrTagExists):
+				s.errorJSON(w, err, http.StatusConflict)
+			default:
+				s.errorJSON(w, err, http.StatusInternalServerError)
+			}
+			return
+		}
+	}
 
 	s.writeJSON(w, http.StatusOK, tag)
 }
 
-func (s *Server) updateTag(w http.ResponseWriter, r *http.Request) {
-	idParam := chi.URLParam(r, "id")
-	id, err := uuid.Parse(idParam)
-	if err != nil {
-		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+// tagExists answers a rename onto the name of another tag, pointing at the
+// merge that would combine them.
+func (s *Server) tagExists(w http.ResponseWriter, r *http.Request, tag, existing *models.Tag) {
+	locale := r.Context().Value(localeKey).(string)
+	s.writeJSON(w, http.StatusConflict, map[string]any{
+		"statusCode": http.StatusConflict,
+		"message":    fmt.Sprintf("Tag with provided name (%s) already exists. Merge into it with ?merge=true.", existing.Name),
+		"error":      http.StatusText(http.StatusConflict),
+		"mergeInto":  existing,
+		"mergeUrl":   fmt.Sprintf("/%s/tags/%s/merge", locale, tag.ID),
+	})
+}
+
+// mergeTag moves every note of the tag onto the target tag and deletes it.
+func (s *Server) mergeTag(w http.ResponseWriter, r *http.Request) {
+	source, ok := s.ownedTag(w, r)
+	if !ok {
 		return
 	}
 
-	var input models.Tag
+	var input struct {
+		TargetID uuid.UUID `json:"targetId" validate:"required"`
+	}
 	if err := s.readJSON(w, r, &input); err != nil {
 		s.errorJSON(w, err, http.StatusBadRequest)
 		return
 	}
+	if err := s.validateStruct(input); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, s.formatValidationErrors(err))
+		return
+	}
+
+	target, err := s.store.Tags.GetByID(r.Context(), input.TargetID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if target == nil || target.UserID != source.UserID {
+		s.errorJSON(w, errors.New("target tag not found"), http.StatusNotFound)
+		return
+	}
 
-	input.ID = id
-	if err := s.store.Tags.Update(r.Context(), &input); err != nil {
+	if err := s.store.Tags.Merge(r.Context(), source, target); err != nil {
+		if errors.Is(err, store.ErrTagCycle) {
+			s.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
 
-	s.writeJSON(w, http.StatusOK, input)
+	s.writeJSON(w, http.StatusOK, target)
 }
 
-func (s *Server) deleteTag(w http.ResponseWriter, r *http.Request) {
-	idParam := chi.URLParam(r, "id")
-	id, err := uuid.Parse(idParam)
+// tagPaths normalizes tag names into paths, dropping empty and repeated
+// ones.
+func tagPaths(names []string) []string {
+	paths := []string{}
+	for _, name := range names {
+		if path := store.TagPath(name); path != "" && !slices.Contains(paths, path) {
+			paths = append(paths, path)
+		}
+	}
+	return paths
+}
+
+// manageTagsPage renders the page for renaming, recoloring, merging and
+// deleting tags.
+func (s *Server) manageTagsPage(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	tags, _, err := s.store.Tags.GetAll(r.Context(), userID, allTags, store.TagFilter{Usage: true})
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+	if tags == nil {
+		tags = []models.Tag{}
+	}
+
+	s.render(w, r, "tags_manage.html", map[string]any{
+		"Title": "tags.manage.title",
+		"Tags":  tags,
+	})
+}
+
+// ownedTag loads the tag in the id URL parameter and checks that it belongs
+// to the current user, writing the error response itself when it cannot.
+func (s *Server) ownedTag(w http.ResponseWriter, r *http.Request) (*models.Tag, bool) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
 	if err != nil {
 		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	tag, err := s.store.Tags.GetByID(r.Context(), id)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return nil, false
+	}
+	if tag == nil || tag.UserID != userID {
+		s.errorJSON(w, errors.New("tag not found"), http.StatusNotFound)
+		return nil, false
+	}
+
+	return tag, true
+}
+
+// deleteTag removes a tag without children. Tags below it have to be
+// deleted or moved first, so a subtree never goes away unnoticed.
+func (s *Server) deleteTag(w http.ResponseWriter, r *http.Request) {
+	tag, ok := s.ownedTag(w, r)
+	if !ok {
 		return
 	}
 
-	if err := s.store.Tags.Delete(r.Context(), id); err != nil {
+	if err := s.store.Tags.Delete(r.Context(), tag.ID); err != nil {
+		if errors.Is(err, store.ErrTagHasChildren) {
+			s.errorJSON(w, err, http.StatusConflict)
+			return
+		}
 		s.errorJSON(w, err, http.StatusInternalServerError)
 		return
 	}
@@ -119,6 +349,22 @@ func (s *Server) deleteTag(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// deleteUnusedTags removes the tags that no note carries, not even one in
+// the trash.
+func (s *Server) deleteUnusedTags(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	deleted, err := s.store.Tags.DeleteUnused(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"deleted": deleted,
+	})
+}
+
 func (s *Server) findTagsOrCreate(w http.ResponseWriter, r *http.Request) {
 	userID := r.Context().Value(UserIDKey).(uuid.UUID)
 	var input struct {
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/language"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/notetemplate"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/store"
+)
+
+var errNoteTemplateNotFound = errors.New("template not found")
+
+type noteTemplateInput struct {
+	Name     string   `json:"name" validate:"required,max=100"`
+	Title    string   `json:"title" validate:"max=255"`
+	Content  string   `json:"content"`
+	Language string   `json:"language"`
+	Tags     []string `json:"tags" validate:"max=50"`
+}
+
+// renderedNoteTemplate is a template with its placeholders filled in, ready
+// to become a note.
+type renderedNoteTemplate struct {
+	Title    string   `json:"title"`
+	Content  string   `json:"content"`
+	Language string   `json:"language"`
+	Tags     []string `json:"tags"`
+}
+
+// renderNoteTemplate fills in the placeholders of tmpl. {{title}} in the
+// template title stands for title, or the template name when it is empty,
+// and in the content for the resulting note title.
+func renderNoteTemplate(tmpl *models.NoteTemplate, title string, now time.Time) renderedNoteTemplate {
+	if title == "" {
+		title = tmpl.Name
+	}
+	if tmpl.Title != "" {
+		title = notetemplate.Render(tmpl.Title, notetemplate.Vars{Now: now, Title: title})
+	}
+
+	return renderedNoteTemplate{
+		Title:    title,
+		Content:  notetemplate.Render(tmpl.Content, notetemplate.Vars{Now: now, Title: title}),
+		Language: tmpl.Language,
+		Tags:     tmpl.Tags,
+	}
+}
+
+// applyNoteTemplateInput validates input and copies it onto tmpl, writing
+// the error response itself when it cannot.
+func (s *Server) applyNoteTemplateInput(w http.ResponseWriter, tmpl *models.NoteTemplate, input noteTemplateInput) bool {
+	if err := s.validateStruct(input); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, s.formatValidationErrors(err))
+		return false
+	}
+
+	if input.Language == "" {
+		input.Language = "auto"
+	}
+	if input.Language != "auto" && !language.IsSupported(input.Language) {
+		s.errorJSON(w, fmt.Errorf("unsupported language (%s)", input.Language), http.StatusBadRequest)
+		return false
+	}
+
+	tmpl.Name = input.Name
+	tmpl.Title = input.Title
+	tmpl.Content = input.Content
+	tmpl.Language = input.Language
+	tmpl.Tags = tagPaths(input.Tags)
+	return true
+}
+
+func (s *Server) getNoteTemplates(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	templates, err := s.store.Templates.GetAll(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, map[string]any{
+		"data":  templates,
+		"count": len(templates),
+	})
+}
+
+func (s *Server) getNoteTemplate(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := s.ownedNoteTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, tmpl)
+}
+
+// createNoteTemplate saves a new template. With fromNote it starts from a
+// copy of that note, and the other fields given override the copy.
+func (s *Server) createNoteTemplate(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	var input struct {
+		noteTemplateInput
+		FromNote *uuid.UUID `json:"fromNote"`
+	}
+	if err := s.readJSON(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if input.FromNote != nil {
+		note, err := s.store.Notes.GetByID(r.Context(), *input.FromNote)
+		if err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+		if note == nil || note.UserID != userID {
+			s.errorJSON(w, errors.New("note not found"), http.StatusNotFound)
+			return
+		}
+
+		if input.Title == "" {
+			input.Title = note.Title
+		}
+		if input.Content == "" {
+			input.Content = note.Content
+		}
+		if input.Language == "" {
+			input.Language = note.Language
+		}
+		if input.Tags == nil {
+			noteTags, err := s.store.Notes.GetTags(r.Context(), note.ID)
+			if err != nil {
+				s.errorJSON(w, err, http.StatusInternalServerError)
+				return
+			}
+			for _, tag := range noteTags {
+				input.Tags = append(input.Tags, tag.Name)
+			}
+		}
+	}
+
+	tmpl := models.NoteTemplate{UserID: userID}
+	if !s.applyNoteTemplateInput(w, &tmpl, input.noteTemplateInput) {
+		return
+	}
+
+	if err := s.store.Templates.Create(r.Context(), &tmpl); err != nil {
+		if errors.Is(err, store.ErrNoteTemplateExists) {
+			s.errorJSON(w, err, http.StatusConflict)
+			return
+		}
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, tmpl)
+}
+
+// updateNoteTemplate applies a JSON merge patch: the fields given in the
+// body change and the rest are kept.
+func (s *Server) updateNoteTemplate(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := s.ownedNoteTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	input := noteTemplateInput{
+		Name:     tmpl.Name,
+		Title:    tmpl.Title,
+		Content:  tmpl.Content,
+		Language: tmpl.Language,
+		Tags:     tmpl.Tags,
+	}
+	if err := s.readNoteTemplatePatch(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+	if !s.applyNoteTemplateInput(w, tmpl, input) {
+		return
+	}
+
+	if err := s.store.Templates.Update(r.Context(), tmpl); err != nil {
+		if errors.Is(err, store.ErrNoteTemplateExists) {
+			s.errorJSON(w, err, http.StatusConflict)
+			return
+		}
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, tmpl)
+}
+
+// readNoteTemplatePatch reads a JSON merge patch onto input. The name
+// cannot be removed; a null title or content clears it, a null language
+// goes back to automatic detection and null tags remove them all.
+func (s *Server) readNoteTemplatePatch(w http.ResponseWriter, r *http.Request, input *noteTemplateInput) error {
+	members, err := s.readMergePatch(w, r, "name", "title", "content", "language", "tags")
+	if err != nil {
+		return err
+	}
+
+	name, err := patchString(members, "name")
+	if err != nil {
+		return err
+	}
+	if name != nil {
+		input.Name = *name
+	}
+
+	clearable := []struct {
+		key   string
+		field *string
+	}{
+		{"title", &input.Title},
+		{"content", &input.Content},
+		// applyNoteTemplateInput turns an empty language into auto.
+		{"language", &input.Language},
+	}
+	for _, member := range clearable {
+		raw, ok := members[member.key]
+		if !ok {
+			continue
+		}
+		*member.field = ""
+		if !isJSONNull(raw) {
+			if err := json.Unmarshal(raw, member.field); err != nil {
+				return fmt.Errorf("invalid %s: %w", member.key, err)
+			}
+		}
+	}
+
+	if raw, ok := members["tags"]; ok {
+		input.Tags = []string{}
+		if !isJSONNull(raw) {
+			if err := json.Unmarshal(raw, &input.Tags); err != nil {
+				return fmt.Errorf("invalid tags: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *Server) deleteNoteTemplate(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := s.ownedNoteTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	if err := s.store.Templates.Delete(r.Context(), tmpl.ID); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// renderNoteTemplateHandler returns the note the template would create now,
+// with the title query parameter standing for {{title}}.
+func (s *Server) renderNoteTemplateHandler(w http.ResponseWriter, r *http.Request) {
+	tmpl, ok := s.ownedNoteTemplate(w, r)
+	if !ok {
+		return
+	}
+
+	loc, err := s.userLocation(r.Context(), tmpl.UserID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusOK, renderNoteTemplate(tmpl, r.URL.Query().Get("title"), time.Now().In(loc)))
+}
+
+// ownedNoteTemplate loads the template in the id URL parameter and checks
+// that it belongs to the current user, writing the error response itself
+// when it cannot.
+func (s *Server) ownedNoteTemplate(w http.ResponseWriter, r *http.Request) (*models.NoteTemplate, bool) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+	id, err := uuid.Parse(chi.URLParam(r, "id"))
+	if err != nil {
+		s.errorJSON(w, errors.New("invalid id"), http.StatusBadRequest)
+		return nil, false
+	}
+
+	tmpl, err := s.store.Templates.GetByID(r.Context(), id)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return nil, false
+	}
+	if tmpl == nil || tmpl.UserID != userID {
+		s.errorJSON(w, errNoteTemplateNotFound, http.StatusNotFound)
+		return nil, false
+	}
+
+	return tmpl, true
+}
@@ -1,7 +1,11 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -16,3 +20,92 @@ func (s *Server) getMe(w http.ResponseWriter, r *http.Request) {
 
 	s.writeJSON(w, http.StatusOK, user)
 }
+
+func (s *Server) updateMe(w http.ResponseWriter, r *http.Request) {
+	userID := r.Context().Value(UserIDKey).(uuid.UUID)
+
+	var input struct {
+		Name              *string `json:"name" validate:"omitempty,min=3,max=50"`
+		RevisionRetention *int    `json:"revisionRetention" validate:"omitempty,min=1,max=500"`
+		// DailyTemplateID picks the template for new daily notes; null
+		// goes back to empty ones.
+		DailyTemplateID json.RawMessage `json:"dailyTemplateId"`
+		// Timezone is an IANA name such as "Europe/Rome".
+		Timezone *string `json:"timezone" validate:"omitempty,timezone"`
+	}
+
+	if err := s.readJSON(w, r, &input); err != nil {
+		s.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if err := s.validateStruct(input); err != nil {
+		s.writeJSON(w, http.StatusBadRequest, s.formatValidationErrors(err))
+		return
+	}
+
+	user, err := s.store.Users.GetByID(r.Context(), userID)
+	if err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+	if input.RevisionRetention != nil {
+		user.RevisionRetention = *input.RevisionRetention
+	}
+	if input.Timezone != nil {
+		user.Timezone = *input.Timezone
+	}
+	if input.DailyTemplateID != nil {
+		user.DailyTemplateID = nil
+		if !isJSONNull(input.DailyTemplateID) {
+			var id uuid.UUID
+			if err := json.Unmarshal(input.DailyTemplateID, &id); err != nil {
+				s.errorJSON(w, errors.New("invalid dailyTemplateId"), http.StatusBadRequest)
+				return
+			}
+			tmpl, err := s.store.Templates.GetByID(r.Context(), id)
+			if err != nil {
+				s.errorJSON(w, err, http.StatusInternalServerError)
+				return
+			}
+			if tmpl == nil || tmpl.UserID != user.ID {
+				s.errorJSON(w, errNoteTemplateNotFound, http.StatusNotFound)
+				return
+			}
+			user.DailyTemplateID = &tmpl.ID
+		}
+	}
+
+	if err := s.store.Users.Update(r.Context(), user); err != nil {
+		s.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	if input.RevisionRetention != nil {
+		if err := s.store.Notes.PruneRevisions(r.Context(), user.ID, user.RevisionRetention); err != nil {
+			s.errorJSON(w, err, http.StatusInternalServerError)
+			return
+		}
+	}
+
+	s.writeJSON(w, http.StatusOK, user)
+}
+
+// userLocation returns the timezone of the user, falling back to UTC when
+// the name is not known to this server.
+func (s *Server) userLocation(ctx context.Context, userID uuid.UUID) (*time.Location, error) {
+	user, err := s.store.Users.GetByID(ctx, userID)
+	if err != nil || user == nil {
+		return time.UTC, err
+	}
+
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.UTC, nil
+	}
+	return loc, nil
+}
@@ -1,12 +1,15 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"regexp"
 	"strings"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/search"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/tagcolor"
 )
 
 var validate *validator.Validate
@@ -14,6 +17,13 @@ var validate *validator.Validate
 func init() {
 	validate = validator.New()
 	validate.RegisterValidation("strongpassword", validateStrongPassword)
+	validate.RegisterValidation("tagcolor", validateTagColor)
+}
+
+// validateTagColor accepts #rgb and #rrggbb hex colors and the names of the
+// tag palette.
+func validateTagColor(fl validator.FieldLevel) bool {
+	return tagcolor.Valid(fl.Field().String())
 }
 
 func validateStrongPassword(fl validator.FieldLevel) bool {
@@ -57,3 +67,39 @@ func (s *Server) formatValidationErrors(err error) map[string]any {
 		"message":    err.Error(),
 	}
 }
+
+// searchErrorMessage translates search query errors, reporting false for
+// any other kind of error.
+func (s *Server) searchErrorMessage(r *http.Request, err error) (string, bool) {
+	var searchErr *search.Error
+	if !errors.As(err, &searchErr) {
+		return "", false
+	}
+
+	locale := r.Context().Value(localeKey).(string)
+	return s.i18n.Translate(locale, searchErr.Key, map[string]any{
+		"Param": searchErr.Param,
+	}), true
+}
+
+func (s *Server) searchErrorJSON(w http.ResponseWriter, r *http.Request, err error) bool {
+	message, ok := s.searchErrorMessage(r, err)
+	if !ok {
+		return false
+	}
+
+	var searchErr *search.Error
+	errors.As(err, &searchErr)
+	s.writeJSON(w, http.StatusBadRequest, map[string]any{
+		"statusCode": http.StatusBadRequest,
+		"message":    message,
+		"errors": map[string]map[string]string{
+			"search": {
+				"key":   searchErr.Key,
+				"field": "search",
+				"param": searchErr.Param,
+			},
+		},
+	})
+	return true
+}
@@ -2,8 +2,10 @@ package store
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/graph"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
 )
 
@@ -13,27 +15,82 @@ type UserStorage interface {
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
 	GetByEmailOrUsername(ctx context.Context, identifier string) (*models.User, error)
+	GetByCalendarTokenHash(ctx context.Context, hash string) (*models.User, error)
+	SetCalendarTokenHash(ctx context.Context, id uuid.UUID, hash *string) error
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id uuid.UUID) error
 }
 
 type NoteStorage interface {
 	Create(ctx context.Context, note *models.Note) error
+	CreateDaily(ctx context.Context, note *models.Note, date time.Time, tagNames []string) (bool, error)
+	GetDaily(ctx context.Context, userID uuid.UUID, date time.Time) (*models.Note, error)
+	GetDailyDates(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]time.Time, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Note, error)
-	GetAll(ctx context.Context, userID uuid.UUID, page, limit int64, search string, tags []string) ([]models.Note, int64, error)
+	GetAll(ctx context.Context, userID uuid.UUID, page Page, filter NoteFilter) ([]models.Note, models.PaginationMetadata, error)
 	Update(ctx context.Context, note *models.Note) error
+	Patch(ctx context.Context, note *models.Note, patch NotePatch) error
+	GetLinks(ctx context.Context, noteID uuid.UUID) ([]models.NoteLink, error)
+	GetBacklinks(ctx context.Context, noteID uuid.UUID) ([]models.Backlink, error)
+	GetDanglingLinks(ctx context.Context, userID uuid.UUID) ([]models.NoteLink, error)
+	GetGraph(ctx context.Context, userID uuid.UUID, filter GraphFilter) (*graph.Graph, error)
+	GetRevisions(ctx context.Context, noteID uuid.UUID) ([]models.NoteRevision, error)
+	GetRevision(ctx context.Context, noteID uuid.UUID, revision int) (*models.NoteRevision, error)
+	PruneRevisions(ctx context.Context, userID uuid.UUID, retention int) error
+	SetArchived(ctx context.Context, id uuid.UUID, archived bool) error
+	SetPinned(ctx context.Context, id uuid.UUID, pinned bool) error
+	SetNotebook(ctx context.Context, id uuid.UUID, notebookID *uuid.UUID) error
+	SetSchedule(ctx context.Context, id uuid.UUID, dueAt, remindAt *time.Time) error
+	GetScheduled(ctx context.Context, userID uuid.UUID, from time.Time) ([]models.Note, error)
+	ClaimReminders(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error)
+	ReleaseReminder(ctx context.Context, noteID uuid.UUID) error
+	Reorder(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	GetTrash(ctx context.Context, userID uuid.UUID, page, limit int64) ([]models.Note, int64, error)
+	GetDeletedByID(ctx context.Context, id uuid.UUID) (*models.Note, error)
+	Restore(ctx context.Context, id uuid.UUID) error
+	Purge(ctx context.Context, id uuid.UUID) error
+	PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error)
 	AttachTags(ctx context.Context, noteID uuid.UUID, tagIDs []uuid.UUID) error
 	DetachTag(ctx context.Context, noteID uuid.UUID, tagID uuid.UUID) error
-	ClearTags(ctx context.Context, noteID uuid.UUID) error
 	GetTags(ctx context.Context, noteID uuid.UUID) ([]models.Tag, error)
 }
 
 type TagStorage interface {
 	Create(ctx context.Context, tag *models.Tag) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Tag, error)
-	GetAll(ctx context.Context, userID uuid.UUID, page, limit int64) ([]models.Tag, int64, error)
+	GetAll(ctx context.Context, userID uuid.UUID, page Page, filter TagFilter) ([]models.Tag, models.PaginationMetadata, error)
+	GetByName(ctx context.Context, userID uuid.UUID, name string) (*models.Tag, error)
+	GetTree(ctx context.Context, userID uuid.UUID) ([]models.TagNode, error)
 	Update(ctx context.Context, tag *models.Tag) error
+	Patch(ctx context.Context, tag *models.Tag, patch TagPatch) error
+	Merge(ctx context.Context, source, target *models.Tag) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	DeleteUnused(ctx context.Context, userID uuid.UUID) (int64, error)
 	FindOrCreate(ctx context.Context, userID uuid.UUID, names []string) ([]models.Tag, error)
 }
+
+type NotebookStorage interface {
+	Create(ctx context.Context, notebook *models.Notebook) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Notebook, error)
+	GetAll(ctx context.Context, userID uuid.UUID) ([]models.Notebook, error)
+	Rename(ctx context.Context, notebook *models.Notebook, name string) error
+	Move(ctx context.Context, notebook *models.Notebook, parentID *uuid.UUID) error
+	Delete(ctx context.Context, id uuid.UUID, mode NotebookDeleteMode) error
+}
+
+type SavedSearchStorage interface {
+	Create(ctx context.Context, search *models.SavedSearch) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.SavedSearch, error)
+	GetAll(ctx context.Context, userID uuid.UUID) ([]models.SavedSearch, error)
+	Update(ctx context.Context, search *models.SavedSearch) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+type NoteTemplateStorage interface {
+	Create(ctx context.Context, tmpl *models.NoteTemplate) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.NoteTemplate, error)
+	GetAll(ctx context.Context, userID uuid.UUID) ([]models.NoteTemplate, error)
+	Update(ctx context.Context, tmpl *models.NoteTemplate) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
@@ -4,14 +4,98 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"html"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/language"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/search"
 )
 
+// ts_headline marks matches with private-use runes so the snippet can be
+// HTML-escaped before the markers are turned into <mark> elements.
+const (
+	highlightStart = "\uE000"
+	highlightStop  = "\uE001"
+)
+
+var (
+	titleHeadlineOptions   = fmt.Sprintf(`StartSel="%s", StopSel="%s", HighlightAll=true`, highlightStart, highlightStop)
+	contentHeadlineOptions = fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxFragments=2, MaxWords=25, MinWords=8, FragmentDelimiter=" … "`, highlightStart, highlightStop)
+)
+
+type ArchiveFilter string
+
+const (
+	ArchiveActive   ArchiveFilter = "active"
+	ArchiveArchived ArchiveFilter = "archived"
+	ArchiveAll      ArchiveFilter = "all"
+)
+
+// TagMatch decides whether a note needs any or all of the filter tags.
+type TagMatch string
+
+const (
+	TagMatchAny TagMatch = "any"
+	TagMatchAll TagMatch = "all"
+)
+
+// NoteFilter narrows the notes returned by GetAll. The words in Query are
+// parsed for Locale as well as for the other supported languages. Tags
+// keeps the notes carrying a tag at or below any of the paths, or each of
+// them when TagMatch is TagMatchAll. Archived defaults to active notes.
+// Notebook keeps the notes filed directly in that notebook, or anywhere
+// below it with NotebookDescendants. Sort is not a filter but travels with
+// it, since the default order depends on the query.
+type NoteFilter struct {
+	Query               *search.Query
+	Tags                []string
+	TagMatch            TagMatch
+	Locale              string
+	Archived            ArchiveFilter
+	Notebook            *uuid.UUID
+	NotebookDescendants bool
+	Sort                NoteSort
+}
+
+// ErrNoteNotFound is returned when a note given by id does not exist or
+// belongs to another user.
+var ErrNoteNotFound = errors.New("note not found")
+
+// ErrVersionConflict is returned by Update and Patch when the note was changed after
+// the version the caller read.
+var ErrVersionConflict = errors.New("note version conflict")
+
+const noteColumns = `n.id, n.user_id, n.notebook_id, n.title, n.content, n.language, n.archived, n.pinned, n.position, n.version, n.created_at, n.updated_at, n.deleted_at, n.due_at, n.remind_at, n.reminded_at`
+
+// noteScanFields returns the scan destinations matching noteColumns.
+func noteScanFields(note *models.Note) []any {
+	return []any{
+		&note.ID,
+		&note.UserID,
+		&note.NotebookID,
+		&note.Title,
+		&note.Content,
+		&note.Language,
+		&note.Archived,
+		&note.Pinned,
+		&note.Position,
+		&note.Version,
+		&note.CreatedAt,
+		&note.UpdatedAt,
+		&note.DeletedAt,
+		&note.DueAt,
+		&note.RemindAt,
+		&note.RemindedAt,
+	}
+}
+
 type PostgresNoteStore struct {
 	pool *pgxpool.Pool
 }
@@ -23,25 +107,63 @@ func NewNoteStore(pool *pgxpool.Pool) *PostgresNoteStore {
 }
 
 func (s *PostgresNoteStore) Create(ctx context.Context, note *models.Note) error {
+	_, err := s.create(ctx, note, nil, nil)
+	return err
+}
+
+// CreateDaily inserts note as the daily note of its user for date, tagged
+// with tagNames in the same transaction. It reports false when that day
+// already has a note, which is left untouched, so concurrent requests for
+// the same day create it only once.
+func (s *PostgresNoteStore) CreateDaily(ctx context.Context, note *models.Note, date time.Time, tagNames []string) (bool, error) {
+	return s.create(ctx, note, &date, tagNames)
+}
+
+func (s *PostgresNoteStore) create(ctx context.Context, note *models.Note, dailyDate *time.Time, tagNames []string) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		INSERT INTO notes (user_id, title, content, archived, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id
+		INSERT INTO notes (user_id, notebook_id, title, content, language, archived, daily_date, position, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, (SELECT COALESCE(MIN(position), 1) - 1 FROM notes WHERE user_id = $1), $8, $9)
+		ON CONFLICT (user_id, daily_date) DO NOTHING
+		RETURNING id, position, version
 	`
 	now := time.Now()
-	note.CreatedAt = now
-	note.UpdatedAt = now
 
-	err := s.pool.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		note.UserID,
+		note.NotebookID,
 		note.Title,
 		note.Content,
+		note.Language,
 		note.Archived,
-		note.CreatedAt,
-		note.UpdatedAt,
-	).Scan(&note.ID)
+		dailyDate,
+		now,
+		now,
+	).Scan(&note.ID, &note.Position, &note.Version)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	note.CreatedAt = now
+	note.UpdatedAt = now
 
-	return err
+	if err := saveLinks(ctx, tx, note.ID, note.Content); err != nil {
+		return false, err
+	}
+	if len(tagNames) > 0 {
+		if err := replaceNoteTags(ctx, tx, note.UserID, note.ID, tagNames, now); err != nil {
+			return false, err
+		}
+	}
+
+	return true, tx.Commit(ctx)
 }
 
 func (s *PostgresNoteStore) AttachTags(ctx context.Context, noteID uuid.UUID, tagIDs []uuid.UUID) error {
@@ -56,9 +178,13 @@ func (s *PostgresNoteStore) AttachTags(ctx context.Context, noteID uuid.UUID, ta
 	defer tx.Rollback(ctx)
 
 	for _, tagID := range tagIDs {
+		// Only tags of the note's owner are attached, others are skipped.
 		query := `
 			INSERT INTO note_tags (note_id, tag_id, created_at)
-			VALUES ($1, $2, $3)
+			SELECT n.id, t.id, $3
+			FROM notes n
+			JOIN tags t ON t.user_id = n.user_id
+			WHERE n.id = $1 AND t.id = $2
 			ON CONFLICT (note_id, tag_id) DO NOTHING
 		`
 		_, err := tx.Exec(ctx, query, noteID, tagID, time.Now())
@@ -98,28 +224,54 @@ func (s *PostgresNoteStore) DetachTag(ctx context.Context, noteID uuid.UUID, tag
 	return tx.Commit(ctx)
 }
 
-func (s *PostgresNoteStore) ClearTags(ctx context.Context, noteID uuid.UUID) error {
-	query := `DELETE FROM note_tags WHERE note_id = $1`
-	_, err := s.pool.Exec(ctx, query, noteID)
-	return err
+func (s *PostgresNoteStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Note, error) {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM notes n
+		WHERE n.id = $1 AND n.deleted_at IS NULL
+	`
+	return s.getOne(ctx, query, id)
 }
 
-func (s *PostgresNoteStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Note, error) {
+func (s *PostgresNoteStore) GetDeletedByID(ctx context.Context, id uuid.UUID) (*models.Note, error) {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM notes n
+		WHERE n.id = $1 AND n.deleted_at IS NOT NULL
+	`
+	return s.getOne(ctx, query, id)
+}
+
+// GetDaily returns the daily note of the user for date, even when it is in
+// the trash, or nil when the day has none.
+func (s *PostgresNoteStore) GetDaily(ctx context.Context, userID uuid.UUID, date time.Time) (*models.Note, error) {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM notes n
+		WHERE n.user_id = $1 AND n.daily_date = $2
+	`
+	return s.getOne(ctx, query, userID, date)
+}
+
+// GetDailyDates lists the days from from to to, both included, whose daily
+// note is not in the trash.
+func (s *PostgresNoteStore) GetDailyDates(ctx context.Context, userID uuid.UUID, from, to time.Time) ([]time.Time, error) {
 	query := `
-		SELECT id, user_id, title, content, archived, created_at, updated_at
+		SELECT daily_date
 		FROM notes
-		WHERE id = $1
+		WHERE user_id = $1 AND daily_date BETWEEN $2 AND $3 AND deleted_at IS NULL
+		ORDER BY daily_date
 	`
+	rows, err := s.pool.Query(ctx, query, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[time.Time])
+}
+
+func (s *PostgresNoteStore) getOne(ctx context.Context, query string, args ...any) (*models.Note, error) {
 	var note models.Note
-	err := s.pool.QueryRow(ctx, query, id).Scan(
-		&note.ID,
-		&note.UserID,
-		&note.Title,
-		&note.Content,
-		&note.Archived,
-		&note.CreatedAt,
-		&note.UpdatedAt,
-	)
+	err := s.pool.QueryRow(ctx, query, args...).Scan(noteScanFields(&note)...)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -130,108 +282,702 @@ func (s *PostgresNoteStore) GetByID(ctx context.Context, id uuid.UUID) (*models.
 	return &note, nil
 }
 
-func (s *PostgresNoteStore) GetAll(ctx context.Context, userID uuid.UUID, page, limit int64, search string, tags []string) ([]models.Note, int64, error) {
-	offset := (page - 1) * limit
+// NoteSort orders the notes returned by GetAll. Pinned notes come first in
+// every order but relevance.
+type NoteSort string
+
+const (
+	NoteSortPosition  NoteSort = "position"
+	NoteSortUpdated   NoteSort = "updated"
+	NoteSortCreated   NoteSort = "created"
+	NoteSortTitle     NoteSort = "title"
+	NoteSortRelevance NoteSort = "relevance"
+)
+
+// keysetTime formats timestamps for cursors at the microsecond precision
+// Postgres keeps.
+const keysetTime = "2006-01-02T15:04:05.999999"
 
+// noteKeyset returns the ordering for sort. rank is the ranking expression
+// of the text query, used only by relevance.
+func noteKeyset(sort NoteSort, rank string) keyset[models.Note] {
+	pinned := sortKey[models.Note]{"n.pinned", "boolean", true, func(n *models.Note) string { return strconv.FormatBool(n.Pinned) }}
+	updated := sortKey[models.Note]{"n.updated_at", "timestamp", true, func(n *models.Note) string { return n.UpdatedAt.Format(keysetTime) }}
+	id := func(desc bool) sortKey[models.Note] {
+		return sortKey[models.Note]{"n.id", "uuid", desc, func(n *models.Note) string { return n.ID.String() }}
+	}
+
+	switch sort {
+	case NoteSortUpdated:
+		return keyset[models.Note]{string(sort), []sortKey[models.Note]{pinned, updated, id(true)}}
+	case NoteSortCreated:
+		created := sortKey[models.Note]{"n.created_at", "timestamp", true, func(n *models.Note) string { return n.CreatedAt.Format(keysetTime) }}
+		return keyset[models.Note]{string(sort), []sortKey[models.Note]{pinned, created, id(true)}}
+	case NoteSortTitle:
+		title := sortKey[models.Note]{"n.title", "text", false, func(n *models.Note) string { return n.Title }}
+		return keyset[models.Note]{string(sort), []sortKey[models.Note]{pinned, title, id(false)}}
+	case NoteSortRelevance:
+		relevance := sortKey[models.Note]{rank, "real", true, func(n *models.Note) string { return strconv.FormatFloat(float64(n.Rank), 'g', -1, 32) }}
+		return keyset[models.Note]{string(sort), []sortKey[models.Note]{relevance, updated, id(true)}}
+	default:
+		position := sortKey[models.Note]{"n.position", "integer", false, func(n *models.Note) string { return strconv.Itoa(n.Position) }}
+		return keyset[models.Note]{string(NoteSortPosition), []sortKey[models.Note]{pinned, position, updated, id(false)}}
+	}
+}
+
+// GetAll lists the notes of the user matching filter. Without a sort, text
+// searches are ordered by relevance and everything else by position;
+// relevance without search text falls back to updated.
+func (s *PostgresNoteStore) GetAll(ctx context.Context, userID uuid.UUID, page Page, filter NoteFilter) ([]models.Note, models.PaginationMetadata, error) {
 	baseQuery := `
 		FROM notes n
-		WHERE n.user_id = $1
+		WHERE n.user_id = $1 AND n.deleted_at IS NULL
 	`
 	args := []any{userID}
 	argCount := 1
 
-	if search != "" {
+	if !filter.Query.IsEmpty() {
+		where, queryArgs, err := filter.Query.SQL(noteSearchSchema(filter.Locale), argCount)
+		if err != nil {
+			return nil, models.PaginationMetadata{}, err
+		}
+		baseQuery += " AND " + where
+		args = append(args, queryArgs...)
+		argCount += len(queryArgs)
+	}
+
+	switch filter.Archived {
+	case ArchiveAll:
+	case ArchiveArchived:
+		baseQuery += " AND n.archived"
+	default:
+		// An explicit is:archived in the query takes over the default.
+		if !filter.Query.HasFlag("archived") {
+			baseQuery += " AND NOT n.archived"
+		}
+	}
+
+	if len(filter.Tags) > 0 {
+		paths := make([]string, len(filter.Tags))
+		for i, name := range filter.Tags {
+			paths[i] = TagPath(name)
+		}
+
 		argCount++
-		baseQuery += fmt.Sprintf(" AND n.title ILIKE $%d", argCount)
-		args = append(args, "%"+search+"%")
+		if filter.TagMatch == TagMatchAll {
+			baseQuery += fmt.Sprintf(`
+				AND NOT EXISTS (
+					SELECT 1 FROM unnest($%d::text[]) f(path)
+					WHERE NOT EXISTS (
+						SELECT 1 FROM note_tags nt
+						JOIN tags t ON t.id = nt.tag_id
+						WHERE nt.note_id = n.id
+						AND t.user_id = $1
+						AND %s
+					)
+				)
+			`, argCount, tagPathMatch("t.name", "f.path"))
+		} else {
+			baseQuery += fmt.Sprintf(`
+				AND EXISTS (
+					SELECT 1 FROM note_tags nt
+					JOIN tags t ON t.id = nt.tag_id
+					WHERE nt.note_id = n.id
+					AND t.user_id = $1
+					AND EXISTS (SELECT 1 FROM unnest($%d::text[]) f(path) WHERE %s)
+				)
+			`, argCount, tagPathMatch("t.name", "f.path"))
+		}
+		args = append(args, paths)
 	}
 
-	if len(tags) > 0 {
+	if filter.Notebook != nil {
 		argCount++
-		baseQuery += fmt.Sprintf(`
-			AND EXISTS (
-				SELECT 1 FROM note_tags nt
-				JOIN tags t ON t.id = nt.tag_id
-				WHERE nt.note_id = n.id
-				AND t.name = ANY($%d)
-				AND t.user_id = $1
-			)
-		`, argCount)
-		args = append(args, tags)
+		if filter.NotebookDescendants {
+			baseQuery += fmt.Sprintf(" AND n.notebook_id IN (%s)", notebookSubtree(fmt.Sprintf("$%d", argCount)))
+		} else {
+			baseQuery += fmt.Sprintf(" AND n.notebook_id = $%d", argCount)
+		}
+		args = append(args, *filter.Notebook)
 	}
 
-	countQuery := "SELECT COUNT(*) " + baseQuery
 	var total int64
-	err := s.pool.QueryRow(ctx, countQuery, args...).Scan(&total)
-	if err != nil {
-		return nil, 0, err
+	if !page.Keyset {
+		countQuery := "SELECT COUNT(*) " + baseQuery
+		if err := s.pool.QueryRow(ctx, countQuery, args...).Scan(&total); err != nil {
+			return nil, models.PaginationMetadata{}, err
+		}
 	}
 
-	dataQuery := `
-		SELECT n.id, n.user_id, n.title, n.content, n.archived, n.created_at, n.updated_at
-	` + baseQuery + `
-		ORDER BY n.updated_at DESC
-		LIMIT $` + fmt.Sprintf("%d", argCount+1) + ` OFFSET $` + fmt.Sprintf("%d", argCount+2)
+	text := filter.Query.TextQuery()
+	sort := filter.Sort
+	switch {
+	case sort == "" && text != "":
+		sort = NoteSortRelevance
+	case sort == NoteSortRelevance && text == "":
+		sort = NoteSortUpdated
+	}
+
+	rankColumns := `, 0::real, '', ''`
+	tsQuery := ""
+	if text != "" {
+		args = append(args, text, titleHeadlineOptions, contentHeadlineOptions, highlightStart+highlightStop)
+		tsQuery = noteTSQuery(filter.Locale, fmt.Sprintf("$%d", argCount+1))
+		// Marker runes already in the note are dropped, or they would turn
+		// into unbalanced <mark> elements.
+		rankColumns = fmt.Sprintf(`,
+			ts_rank_cd(n.search_vector, %[1]s),
+			ts_headline(note_search_config(n.language), translate(n.title, $%[4]d, ''), %[1]s, $%[2]d),
+			ts_headline(note_search_config(n.language), translate(n.content, $%[4]d, ''), %[1]s, $%[3]d)
+		`, tsQuery, argCount+2, argCount+3, argCount+4)
+		argCount += 4
+	}
+	order := noteKeyset(sort, "ts_rank_cd(n.search_vector, "+tsQuery+")")
+
+	limit := page.Limit
+	if page.Keyset {
+		if page.Cursor != "" {
+			cursorArgs, err := order.decode(page.Cursor)
+			if err != nil {
+				return nil, models.PaginationMetadata{}, err
+			}
+			baseQuery += " AND " + order.after(argCount+1)
+			args = append(args, cursorArgs...)
+			argCount += len(cursorArgs)
+		}
+		// One row more than asked for tells whether another page follows.
+		limit++
+	}
 
-	args = append(args, limit, offset)
+	dataQuery := `
+		SELECT ` + noteColumns + rankColumns +
+		baseQuery + `
+		ORDER BY ` + order.orderBy() + `
+		LIMIT $` + fmt.Sprintf("%d", argCount+1)
+	args = append(args, limit)
+	if !page.Keyset {
+		dataQuery += ` OFFSET $` + fmt.Sprintf("%d", argCount+2)
+		args = append(args, page.offset())
+	}
 
 	rows, err := s.pool.Query(ctx, dataQuery, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, models.PaginationMetadata{}, err
 	}
 	defer rows.Close()
 
 	var notes []models.Note
 	for rows.Next() {
 		var note models.Note
-		err := rows.Scan(
-			&note.ID,
-			&note.UserID,
-			&note.Title,
-			&note.Content,
-			&note.Archived,
-			&note.CreatedAt,
-			&note.UpdatedAt,
-		)
+		var titleHeadline, contentHeadline string
+		err := rows.Scan(append(noteScanFields(&note), &note.Rank, &titleHeadline, &contentHeadline)...)
 		if err != nil {
-			return nil, 0, err
+			return nil, models.PaginationMetadata{}, err
+		}
+		if tsQuery != "" {
+			note.TitleHighlight = highlightHTML(titleHeadline)
+			note.ContentHighlight = highlightHTML(contentHeadline)
 		}
 		notes = append(notes, note)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, models.PaginationMetadata{}, err
 	}
 
-	return notes, total, nil
+	if page.Keyset {
+		notes, meta := keysetMetadata(notes, page, order)
+		return notes, meta, nil
+	}
+	return notes, offsetMetadata(page, total, order.name), nil
+}
+
+// NotePatch lists the note fields to change. Nil fields keep their current
+// value. Tags replaces the tags of the note with the ones at those paths,
+// creating any that do not exist yet.
+type NotePatch struct {
+	Title    *string
+	Content  *string
+	Language *string
+	Tags     *[]string
 }
 
+// Update saves every editable field of the note. See Patch.
 func (s *PostgresNoteStore) Update(ctx context.Context, note *models.Note) error {
-	note.UpdatedAt = time.Now()
+	return s.Patch(ctx, note, NotePatch{
+		Title:    &note.Title,
+		Content:  &note.Content,
+		Language: &note.Language,
+	})
+}
+
+// Patch changes the fields set in patch if the note is still at
+// note.Version, and reloads note with the result. When the title or content
+// change, the previous text is kept as a revision in the same transaction
+// and revisions beyond the owner's retention are pruned. Links are parsed
+// again when the content changes. Any patch, even one of the tags alone,
+// moves the note to a new version.
+func (s *PostgresNoteStore) Patch(ctx context.Context, note *models.Note, patch NotePatch) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	var previous models.Note
+	var retention int
+	lockQuery := `
+		SELECT n.title, n.content, n.version, u.revision_retention
+		FROM notes n
+		JOIN users u ON u.id = n.user_id
+		WHERE n.id = $1
+		FOR UPDATE OF n
+	`
+	err = tx.QueryRow(ctx, lockQuery, note.ID).Scan(&previous.Title, &previous.Content, &previous.Version, &retention)
+	if err != nil {
+		return err
+	}
+	if previous.Version != note.Version {
+		return ErrVersionConflict
+	}
+
+	now := time.Now()
+
+	titleChanged := patch.Title != nil && *patch.Title != previous.Title
+	contentChanged := patch.Content != nil && *patch.Content != previous.Content
+	if titleChanged || contentChanged {
+		if err := saveRevision(ctx, tx, note.ID, &previous, now, retention); err != nil {
+			return err
+		}
+	}
+
+	var sets []string
+	var args []any
+	set := func(column string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	if patch.Title != nil {
+		set("title", *patch.Title)
+	}
+	if patch.Content != nil {
+		set("content", *patch.Content)
+	}
+	if patch.Language != nil {
+		set("language", *patch.Language)
+	}
+	set("updated_at", now)
+	sets = append(sets, "version = version + 1")
+
+	args = append(args, note.ID)
+	query := fmt.Sprintf(`
+		UPDATE notes n
+		SET %s
+		WHERE n.id = $%d
+		RETURNING %s
+	`, strings.Join(sets, ", "), len(args), noteColumns)
+
+	if err := tx.QueryRow(ctx, query, args...).Scan(noteScanFields(note)...); err != nil {
+		return err
+	}
+
+	if contentChanged {
+		if err := saveLinks(ctx, tx, note.ID, note.Content); err != nil {
+			return err
+		}
+	}
+
+	if patch.Tags != nil {
+		if err := replaceNoteTags(ctx, tx, note.UserID, note.ID, *patch.Tags, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// replaceNoteTags gives the note exactly the tags at the given paths.
+func replaceNoteTags(ctx context.Context, tx pgx.Tx, userID, noteID uuid.UUID, names []string, now time.Time) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM note_tags WHERE note_id = $1`, noteID); err != nil {
+		return err
+	}
+
+	tags, err := findOrCreateTags(ctx, tx, userID, names, now)
+	if err != nil {
+		return err
+	}
+
+	for _, tag := range tags {
+		query := `
+			INSERT INTO note_tags (note_id, tag_id, created_at)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (note_id, tag_id) DO NOTHING
+		`
+		if _, err := tx.Exec(ctx, query, noteID, tag.ID, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func saveRevision(ctx context.Context, tx pgx.Tx, noteID uuid.UUID, previous *models.Note, createdAt time.Time, retention int) error {
+	insertQuery := `
+		INSERT INTO note_revisions (note_id, revision, title, content, created_at)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4
+		FROM note_revisions
+		WHERE note_id = $1
+	`
+	_, err := tx.Exec(ctx, insertQuery, noteID, previous.Title, previous.Content, createdAt)
+	if err != nil {
+		return err
+	}
+
+	return pruneRevisions(ctx, tx, "r.note_id = $1", noteID, retention)
+}
+
+// pruneRevisions keeps the newest revisions of every note matched by where,
+// which receives its argument as $1 and the retention as $2.
+func pruneRevisions(ctx context.Context, db interface {
+	Exec(context.Context, string, ...any) (pgconn.CommandTag, error)
+}, where string, arg any, retention int) error {
 	query := `
-		UPDATE notes
-		SET title = $1, content = $2, archived = $3, updated_at = $4
-		WHERE id = $5
+		DELETE FROM note_revisions
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT r.id, ROW_NUMBER() OVER (PARTITION BY r.note_id ORDER BY r.revision DESC) AS position
+				FROM note_revisions r
+				JOIN notes n ON n.id = r.note_id
+				WHERE ` + where + `
+			) ranked
+			WHERE position > $2
+		)
 	`
-	_, err := s.pool.Exec(ctx, query,
-		note.Title,
-		note.Content,
-		note.Archived,
-		note.UpdatedAt,
-		note.ID,
+	_, err := db.Exec(ctx, query, arg, retention)
+	return err
+}
+
+func (s *PostgresNoteStore) PruneRevisions(ctx context.Context, userID uuid.UUID, retention int) error {
+	return pruneRevisions(ctx, s.pool, "n.user_id = $1", userID, retention)
+}
+
+func (s *PostgresNoteStore) GetRevisions(ctx context.Context, noteID uuid.UUID) ([]models.NoteRevision, error) {
+	query := `
+		SELECT id, note_id, revision, title, content, created_at
+		FROM note_revisions
+		WHERE note_id = $1
+		ORDER BY revision DESC
+	`
+
+	rows, err := s.pool.Query(ctx, query, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []models.NoteRevision
+	for rows.Next() {
+		var revision models.NoteRevision
+		err := rows.Scan(
+			&revision.ID,
+			&revision.NoteID,
+			&revision.Revision,
+			&revision.Title,
+			&revision.Content,
+			&revision.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (s *PostgresNoteStore) GetRevision(ctx context.Context, noteID uuid.UUID, revision int) (*models.NoteRevision, error) {
+	query := `
+		SELECT id, note_id, revision, title, content, created_at
+		FROM note_revisions
+		WHERE note_id = $1 AND revision = $2
+	`
+	var rev models.NoteRevision
+	err := s.pool.QueryRow(ctx, query, noteID, revision).Scan(
+		&rev.ID,
+		&rev.NoteID,
+		&rev.Revision,
+		&rev.Title,
+		&rev.Content,
+		&rev.CreatedAt,
 	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// SetArchived archives or unarchives the note. A change of state counts as
+// an update, so it bumps the version and updated_at.
+func (s *PostgresNoteStore) SetArchived(ctx context.Context, id uuid.UUID, archived bool) error {
+	query := `
+		UPDATE notes
+		SET archived = $1, updated_at = $2, version = version + 1
+		WHERE id = $3 AND archived <> $1
+	`
+	_, err := s.pool.Exec(ctx, query, archived, time.Now(), id)
+	return err
+}
+
+// SetNotebook files the note in a notebook, or at the root when notebookID
+// is nil.
+func (s *PostgresNoteStore) SetNotebook(ctx context.Context, id uuid.UUID, notebookID *uuid.UUID) error {
+	query := `UPDATE notes SET notebook_id = $1 WHERE id = $2`
+	_, err := s.pool.Exec(ctx, query, notebookID, id)
+	return err
+}
+
+// SetPinned pins or unpins the note and moves it to the top of its group,
+// so pinning brings a note to the top of the list.
+func (s *PostgresNoteStore) SetPinned(ctx context.Context, id uuid.UUID, pinned bool) error {
+	query := `
+		UPDATE notes n
+		SET pinned = $1,
+			position = (
+				SELECT COALESCE(MIN(o.position), 1) - 1
+				FROM notes o
+				WHERE o.user_id = n.user_id AND o.pinned = $1 AND o.id <> n.id
+			)
+		WHERE n.id = $2
+	`
+	_, err := s.pool.Exec(ctx, query, pinned, id)
+	return err
+}
+
+// SetSchedule sets the due date and reminder time of the note, either of
+// them nil to clear it. Moving the reminder re-arms it, so a reminder that
+// already fired fires again at its new time.
+func (s *PostgresNoteStore) SetSchedule(ctx context.Context, id uuid.UUID, dueAt, remindAt *time.Time) error {
+	query := `
+		UPDATE notes
+		SET due_at = $1,
+			remind_at = $2,
+			reminded_at = CASE WHEN remind_at IS DISTINCT FROM $2 THEN NULL ELSE reminded_at END
+		WHERE id = $3
+	`
+	_, err := s.pool.Exec(ctx, query, utcTime(dueAt), utcTime(remindAt), id)
+	return err
+}
+
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	utc := t.UTC()
+	return &utc
+}
+
+// GetScheduled lists the notes of the user with a due date or reminder at
+// or after from, soonest first. Notes in the trash are left out.
+func (s *PostgresNoteStore) GetScheduled(ctx context.Context, userID uuid.UUID, from time.Time) ([]models.Note, error) {
+	query := `
+		SELECT ` + noteColumns + `
+		FROM notes n
+		WHERE n.user_id = $1 AND n.deleted_at IS NULL AND (n.due_at >= $2 OR n.remind_at >= $2)
+		ORDER BY COALESCE(n.due_at, n.remind_at), n.id
+	`
+	rows, err := s.pool.Query(ctx, query, userID, from)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notes := []models.Note{}
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(noteScanFields(&note)...); err != nil {
+			return nil, err
+		}
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+// ClaimReminders marks up to limit reminders that are due at now as
+// delivered and returns them. The claim commits before anything is sent,
+// and reminders another replica is claiming are skipped, so each one is
+// handed out only once. Use ReleaseReminder when sending one fails.
+func (s *PostgresNoteStore) ClaimReminders(ctx context.Context, now time.Time, limit int) ([]models.Reminder, error) {
+	query := `
+		WITH due AS (
+			SELECT id
+			FROM notes
+			WHERE remind_at <= $1 AND reminded_at IS NULL AND deleted_at IS NULL
+			ORDER BY remind_at
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE notes n
+		SET reminded_at = $1
+		FROM due, users u
+		WHERE n.id = due.id AND u.id = n.user_id
+		RETURNING n.id, n.user_id, u.email, u.username, COALESCE(u.name, ''), u.timezone, n.title, n.due_at, n.remind_at
+	`
+	rows, err := s.pool.Query(ctx, query, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (models.Reminder, error) {
+		var reminder models.Reminder
+		err := row.Scan(
+			&reminder.NoteID,
+			&reminder.UserID,
+			&reminder.Email,
+			&reminder.Username,
+			&reminder.Name,
+			&reminder.Timezone,
+			&reminder.Title,
+			&reminder.DueAt,
+			&reminder.RemindAt,
+		)
+		return reminder, err
+	})
+}
+
+// ReleaseReminder makes a claimed reminder of the note pending again, so
+// that the next ClaimReminders picks it up.
+func (s *PostgresNoteStore) ReleaseReminder(ctx context.Context, noteID uuid.UUID) error {
+	query := `UPDATE notes SET reminded_at = NULL WHERE id = $1`
+	_, err := s.pool.Exec(ctx, query, noteID)
 	return err
 }
 
+// Reorder puts the user's notes in ids in that order. The notes swap the
+// positions they already hold, so notes left out of ids, such as those on
+// other pages, keep their place.
+func (s *PostgresNoteStore) Reorder(ctx context.Context, userID uuid.UUID, ids []uuid.UUID) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT position
+		FROM notes
+		WHERE user_id = $1 AND id = ANY($2)
+		ORDER BY position
+		FOR UPDATE
+	`
+	rows, err := tx.Query(ctx, query, userID, ids)
+	if err != nil {
+		return err
+	}
+	positions, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return err
+	}
+	if len(positions) != len(ids) {
+		return ErrNoteNotFound
+	}
+
+	updateQuery := `
+		UPDATE notes n
+		SET position = o.position
+		FROM unnest($2::uuid[], $3::integer[]) AS o(id, position)
+		WHERE n.id = o.id AND n.user_id = $1
+	`
+	if _, err := tx.Exec(ctx, updateQuery, userID, ids, positions); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete moves the note to the trash. Use Purge to remove it for good.
 func (s *PostgresNoteStore) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM notes WHERE id = $1`
+	query := `UPDATE notes SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+	_, err := s.pool.Exec(ctx, query, time.Now(), id)
+	return err
+}
+
+func (s *PostgresNoteStore) Restore(ctx context.Context, id uuid.UUID) error {
+	query := `UPDATE notes SET deleted_at = NULL WHERE id = $1`
 	_, err := s.pool.Exec(ctx, query, id)
 	return err
 }
 
+func (s *PostgresNoteStore) Purge(ctx context.Context, id uuid.UUID) error {
+	query := `DELETE FROM notes WHERE id = $1 AND deleted_at IS NOT NULL`
+	_, err := s.pool.Exec(ctx, query, id)
+	return err
+}
+
+func (s *PostgresNoteStore) PurgeDeletedBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	query := `DELETE FROM notes WHERE deleted_at IS NOT NULL AND deleted_at < $1`
+	tag, err := s.pool.Exec(ctx, query, cutoff)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+func (s *PostgresNoteStore) GetTrash(ctx context.Context, userID uuid.UUID, page, limit int64) ([]models.Note, int64, error) {
+	offset := (page - 1) * limit
+
+	countQuery := `
+		SELECT COUNT(*)
+		FROM notes
+		WHERE user_id = $1 AND deleted_at IS NOT NULL
+	`
+	var total int64
+	err := s.pool.QueryRow(ctx, countQuery, userID).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dataQuery := `
+		SELECT ` + noteColumns + `
+		FROM notes n
+		WHERE n.user_id = $1 AND n.deleted_at IS NOT NULL
+		ORDER BY n.deleted_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	rows, err := s.pool.Query(ctx, dataQuery, userID, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var notes []models.Note
+	for rows.Next() {
+		var note models.Note
+		if err := rows.Scan(noteScanFields(&note)...); err != nil {
+			return nil, 0, err
+		}
+		notes = append(notes, note)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return notes, total, nil
+}
+
 func (s *PostgresNoteStore) GetTags(ctx context.Context, noteID uuid.UUID) ([]models.Tag, error) {
 	query := `
-		SELECT t.id, t.user_id, t.name, t.color, t.created_at, t.updated_at
+		SELECT ` + tagColumns + `
 		FROM tags t
 		INNER JOIN note_tags nt ON t.id = nt.tag_id
 		WHERE nt.note_id = $1
@@ -247,15 +993,7 @@ func (s *PostgresNoteStore) GetTags(ctx context.Context, noteID uuid.UUID) ([]mo
 	var tags []models.Tag
 	for rows.Next() {
 		var tag models.Tag
-		err := rows.Scan(
-			&tag.ID,
-			&tag.UserID,
-			&tag.Name,
-			&tag.Color,
-			&tag.CreatedAt,
-			&tag.UpdatedAt,
-		)
-		if err != nil {
+		if err := rows.Scan(tagScanFields(&tag)...); err != nil {
 			return nil, err
 		}
 		tags = append(tags, tag)
@@ -267,3 +1005,61 @@ func (s *PostgresNoteStore) GetTags(ctx context.Context, noteID uuid.UUID) ([]mo
 
 	return tags, nil
 }
+
+// noteTSQuery parses the search text with the request locale and every
+// other supported language, so notes written in another language still
+// match. The query does not depend on the row, which lets the planner use
+// idx_notes_search_vector. The locale is checked against the supported
+// languages before it is inlined.
+func noteTSQuery(locale, arg string) string {
+	if !language.IsSupported(locale) {
+		locale = ""
+	}
+
+	configs := []string{locale}
+	for _, lang := range language.Supported {
+		if lang != locale {
+			configs = append(configs, lang)
+		}
+	}
+	if locale != "" {
+		// The empty language stands for the simple configuration.
+		configs = append(configs, "")
+	}
+
+	queries := make([]string, len(configs))
+	for i, config := range configs {
+		queries[i] = fmt.Sprintf("websearch_to_tsquery(note_search_config('%s'), %s)", config, arg)
+	}
+	return "(" + strings.Join(queries, " || ") + ")"
+}
+
+func noteSearchSchema(locale string) search.Schema {
+	return search.Schema{
+		Text: func(arg string) string {
+			return "n.search_vector @@ " + noteTSQuery(locale, arg)
+		},
+		Tag: func(arg string) string {
+			return fmt.Sprintf(`EXISTS (
+				SELECT 1 FROM note_tags nt
+				JOIN tags t ON t.id = nt.tag_id
+				WHERE nt.note_id = n.id
+				AND %s
+			)`, tagPathMatch("lower(t.name)", "lower("+arg+")"))
+		},
+		Flags: map[string]string{
+			"archived": "n.archived",
+			"pinned":   "n.pinned",
+		},
+		Dates: map[string]string{
+			"created": "n.created_at",
+			"updated": "n.updated_at",
+		},
+	}
+}
+
+func highlightHTML(headline string) string {
+	escaped := html.EscapeString(headline)
+	escaped = strings.ReplaceAll(escaped, highlightStart, "<mark>")
+	return strings.ReplaceAll(escaped, highlightStop, "</mark>")
+}
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/graph"
+)
+
+// GraphFilter narrows the notes in the graph to those with any of Tags, or
+// a tag below one of them, and created within [From, To). Archived and
+// trashed notes are left out.
+type GraphFilter struct {
+	Tags []string
+	From *time.Time
+	To   *time.Time
+}
+
+// GetGraph returns the user's notes connected by the links between them and
+// by the tags they share.
+func (s *PostgresNoteStore) GetGraph(ctx context.Context, userID uuid.UUID, filter GraphFilter) (*graph.Graph, error) {
+	where := "n.user_id = $1 AND n.deleted_at IS NULL AND NOT n.archived"
+	args := []any{userID}
+
+	if len(filter.Tags) > 0 {
+		paths := make([]string, len(filter.Tags))
+		for i, name := range filter.Tags {
+			paths[i] = TagPath(name)
+		}
+
+		args = append(args, paths)
+		where += fmt.Sprintf(`
+			AND EXISTS (
+				SELECT 1 FROM note_tags fnt
+				JOIN tags ft ON ft.id = fnt.tag_id
+				WHERE fnt.note_id = n.id
+				AND EXISTS (SELECT 1 FROM unnest($%d::text[]) f(path) WHERE %s)
+			)
+		`, len(args), tagPathMatch("ft.name", "f.path"))
+	}
+	if filter.From != nil {
+		args = append(args, *filter.From)
+		where += fmt.Sprintf(" AND n.created_at >= $%d", len(args))
+	}
+	if filter.To != nil {
+		args = append(args, *filter.To)
+		where += fmt.Sprintf(" AND n.created_at < $%d", len(args))
+	}
+
+	nodesQuery := `
+		SELECT n.id, n.title, n.created_at, n.updated_at,
+			COALESCE(array_agg(t.name ORDER BY t.name) FILTER (WHERE t.id IS NOT NULL), '{}')
+		FROM notes n
+		LEFT JOIN note_tags nt ON nt.note_id = n.id
+		LEFT JOIN tags t ON t.id = nt.tag_id
+		WHERE ` + where + `
+		GROUP BY n.id
+		ORDER BY n.updated_at DESC
+	`
+
+	rows, err := s.pool.Query(ctx, nodesQuery, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	builder := graph.NewBuilder()
+	for rows.Next() {
+		var node graph.Node
+		if err := rows.Scan(&node.ID, &node.Title, &node.CreatedAt, &node.UpdatedAt, &node.Tags); err != nil {
+			return nil, err
+		}
+		builder.AddNode(node)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	linksQuery := `
+		SELECT l.source_id, t.id, COUNT(*)
+		FROM note_links l
+		JOIN notes s ON s.id = l.source_id
+	` + linkTargetJoin + `
+		WHERE s.user_id = $1 AND s.deleted_at IS NULL AND t.id IS NOT NULL
+		GROUP BY l.source_id, t.id
+	`
+	if err := s.addConnections(ctx, linksQuery, userID, builder.AddLinks); err != nil {
+		return nil, err
+	}
+
+	sharedTagsQuery := `
+		SELECT a.note_id, b.note_id, COUNT(*)
+		FROM note_tags a
+		JOIN note_tags b ON b.tag_id = a.tag_id AND a.note_id < b.note_id
+		JOIN tags t ON t.id = a.tag_id
+		WHERE t.user_id = $1
+		GROUP BY a.note_id, b.note_id
+	`
+	if err := s.addConnections(ctx, sharedTagsQuery, userID, builder.AddSharedTags); err != nil {
+		return nil, err
+	}
+
+	return builder.Graph(), nil
+}
+
+// addConnections passes every (from, to, count) row of query to add.
+func (s *PostgresNoteStore) addConnections(ctx context.Context, query string, userID uuid.UUID, add func(from, to uuid.UUID, count int)) error {
+	rows, err := s.pool.Query(ctx, query, userID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var from, to uuid.UUID
+		var count int
+		if err := rows.Scan(&from, &to, &count); err != nil {
+			return err
+		}
+		add(from, to, count)
+	}
+
+	return rows.Err()
+}
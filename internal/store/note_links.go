@@ -0,0 +1,144 @@
+package store
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/wikilink"
+)
+
+// linkTargetJoin resolves the target of link l, written by the owner of
+// source note s, to a note of theirs as t. Targets are matched against ids
+// first and titles second, at query time, so renaming a note changes what
+// links to it without touching the content of other notes. Targets shaped
+// like a UUID are cast so that both lookups can use an index.
+const linkTargetJoin = `
+	LEFT JOIN LATERAL (
+		SELECT n.id, n.title
+		FROM notes n
+		WHERE n.user_id = s.user_id
+		AND n.deleted_at IS NULL
+		AND (
+			n.id = CASE WHEN l.target ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$' THEN l.target::uuid END
+			OR lower(n.title) = lower(l.target)
+		)
+		ORDER BY n.id::text = lower(l.target) DESC, n.updated_at DESC
+		LIMIT 1
+	) t ON TRUE
+`
+
+// saveLinks replaces the links stored for a note with the ones in content.
+func saveLinks(ctx context.Context, tx pgx.Tx, noteID uuid.UUID, content string) error {
+	_, err := tx.Exec(ctx, `DELETE FROM note_links WHERE source_id = $1`, noteID)
+	if err != nil {
+		return err
+	}
+
+	for position, target := range wikilink.Targets(content) {
+		query := `
+			INSERT INTO note_links (source_id, target, position)
+			VALUES ($1, $2, $3)
+		`
+		if _, err := tx.Exec(ctx, query, noteID, target, position); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *PostgresNoteStore) GetLinks(ctx context.Context, noteID uuid.UUID) ([]models.NoteLink, error) {
+	query := `
+		SELECT l.source_id, l.target, t.id, t.title
+		FROM note_links l
+		JOIN notes s ON s.id = l.source_id
+	` + linkTargetJoin + `
+		WHERE l.source_id = $1
+		ORDER BY l.position
+	`
+	return s.queryLinks(ctx, query, noteID)
+}
+
+// GetDanglingLinks returns the links in the user's notes that match no note.
+func (s *PostgresNoteStore) GetDanglingLinks(ctx context.Context, userID uuid.UUID) ([]models.NoteLink, error) {
+	query := `
+		SELECT l.source_id, l.target, t.id, t.title
+		FROM note_links l
+		JOIN notes s ON s.id = l.source_id
+	` + linkTargetJoin + `
+		WHERE s.user_id = $1 AND s.deleted_at IS NULL AND t.id IS NULL
+		ORDER BY s.updated_at DESC, l.position
+	`
+	return s.queryLinks(ctx, query, userID)
+}
+
+func (s *PostgresNoteStore) queryLinks(ctx context.Context, query string, args ...any) ([]models.NoteLink, error) {
+	rows, err := s.pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var links []models.NoteLink
+	for rows.Next() {
+		var link models.NoteLink
+		if err := rows.Scan(&link.SourceID, &link.Target, &link.NoteID, &link.Title); err != nil {
+			return nil, err
+		}
+		link.Dangling = link.NoteID == nil
+		links = append(links, link)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// GetBacklinks returns the notes with a link that resolves to the note.
+// Only links whose target is the id or title of the note can, so those are
+// looked up by index first and then resolved to rule out links that go to
+// another note with the same title.
+func (s *PostgresNoteStore) GetBacklinks(ctx context.Context, noteID uuid.UUID) ([]models.Backlink, error) {
+	query := `
+		WITH target AS (
+			SELECT user_id, ARRAY[id::text, lower(title)] AS keys
+			FROM notes
+			WHERE id = $1
+		)
+		SELECT DISTINCT s.id, s.title, s.updated_at
+		FROM target
+		JOIN note_links l ON lower(l.target) = ANY(target.keys)
+		JOIN notes s ON s.id = l.source_id
+	` + linkTargetJoin + `
+		WHERE s.user_id = target.user_id
+		AND s.deleted_at IS NULL
+		AND s.id <> $1
+		AND t.id = $1
+		ORDER BY s.updated_at DESC
+	`
+
+	rows, err := s.pool.Query(ctx, query, noteID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backlinks []models.Backlink
+	for rows.Next() {
+		var backlink models.Backlink
+		if err := rows.Scan(&backlink.NoteID, &backlink.Title, &backlink.UpdatedAt); err != nil {
+			return nil, err
+		}
+		backlinks = append(backlinks, backlink)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return backlinks, nil
+}
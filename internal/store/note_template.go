@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+)
+
+// ErrNoteTemplateExists is returned when the user already has a template
+// with the same name.
+var ErrNoteTemplateExists = errors.New("template already exists")
+
+const noteTemplateColumns = `id, user_id, name, title, content, language, tags, created_at, updated_at`
+
+func noteTemplateScanFields(tmpl *models.NoteTemplate) []any {
+	return []any{
+		&tmpl.ID,
+		&tmpl.UserID,
+		&tmpl.Name,
+		&tmpl.Title,
+		&tmpl.Content,
+		&tmpl.Language,
+		&tmpl.Tags,
+		&tmpl.CreatedAt,
+		&tmpl.UpdatedAt,
+	}
+}
+
+type PostgresNoteTemplateStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewNoteTemplateStore(pool *pgxpool.Pool) *PostgresNoteTemplateStore {
+	return &PostgresNoteTemplateStore{
+		pool: pool,
+	}
+}
+
+func (s *PostgresNoteTemplateStore) Create(ctx context.Context, tmpl *models.NoteTemplate) error {
+	query := `
+		INSERT INTO note_templates (user_id, name, title, content, language, tags, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	now := time.Now()
+	tmpl.CreatedAt = now
+	tmpl.UpdatedAt = now
+	if tmpl.Tags == nil {
+		tmpl.Tags = []string{}
+	}
+
+	err := s.pool.QueryRow(ctx, query,
+		tmpl.UserID,
+		tmpl.Name,
+		tmpl.Title,
+		tmpl.Content,
+		tmpl.Language,
+		tmpl.Tags,
+		tmpl.CreatedAt,
+		tmpl.UpdatedAt,
+	).Scan(&tmpl.ID)
+	if isUniqueViolation(err) {
+		return ErrNoteTemplateExists
+	}
+	return err
+}
+
+func (s *PostgresNoteTemplateStore) GetByID(ctx context.Context, id uuid.UUID) (*models.NoteTemplate, error) {
+	query := `SELECT ` + noteTemplateColumns + ` FROM note_templates WHERE id = $1`
+
+	var tmpl models.NoteTemplate
+	err := s.pool.QueryRow(ctx, query, id).Scan(noteTemplateScanFields(&tmpl)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &tmpl, nil
+}
+
+// GetAll lists the templates of the user by name.
+func (s *PostgresNoteTemplateStore) GetAll(ctx context.Context, userID uuid.UUID) ([]models.NoteTemplate, error) {
+	query := `
+		SELECT ` + noteTemplateColumns + `
+		FROM note_templates
+		WHERE user_id = $1
+		ORDER BY name, id
+	`
+	rows, err := s.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []models.NoteTemplate{}
+	for rows.Next() {
+		var tmpl models.NoteTemplate
+		if err := rows.Scan(noteTemplateScanFields(&tmpl)...); err != nil {
+			return nil, err
+		}
+		templates = append(templates, tmpl)
+	}
+
+	return templates, rows.Err()
+}
+
+// Update stores every field of the template but its owner.
+func (s *PostgresNoteTemplateStore) Update(ctx context.Context, tmpl *models.NoteTemplate) error {
+	query := `
+		UPDATE note_templates
+		SET name = $1, title = $2, content = $3, language = $4, tags = $5, updated_at = $6
+		WHERE id = $7
+		RETURNING updated_at
+	`
+	if tmpl.Tags == nil {
+		tmpl.Tags = []string{}
+	}
+
+	err := s.pool.QueryRow(ctx, query,
+		tmpl.Name,
+		tmpl.Title,
+		tmpl.Content,
+		tmpl.Language,
+		tmpl.Tags,
+		time.Now(),
+		tmpl.ID,
+	).Scan(&tmpl.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrNoteTemplateExists
+	}
+	return err
+}
+
+func (s *PostgresNoteTemplateStore) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM note_templates WHERE id = $1`, id)
+	return err
+}
@@ -0,0 +1,239 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+)
+
+// ErrNotebookExists is returned when a notebook with the same name already
+// sits under the same parent.
+var ErrNotebookExists = errors.New("notebook already exists")
+
+// ErrNotebookCycle is returned by Move when the new parent is the notebook
+// itself or one of its descendants.
+var ErrNotebookCycle = errors.New("notebook cannot be moved into itself")
+
+// NotebookDeleteMode decides what happens to the notes filed in a deleted
+// notebook and its descendants, which are always deleted with it.
+type NotebookDeleteMode string
+
+const (
+	// NotebookDeleteToRoot moves the notes to the root.
+	NotebookDeleteToRoot NotebookDeleteMode = "root"
+	// NotebookDeleteCascade moves the notes to the trash.
+	NotebookDeleteCascade NotebookDeleteMode = "cascade"
+)
+
+// notebookSubtree returns a query selecting the id of the notebook bound to
+// arg and the ids of all its descendants.
+func notebookSubtree(arg string) string {
+	return fmt.Sprintf(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM notebooks WHERE id = %s
+			UNION ALL
+			SELECT nb.id FROM notebooks nb JOIN subtree ON nb.parent_id = subtree.id
+		)
+		SELECT id FROM subtree
+	`, arg)
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isForeignKeyViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23503"
+}
+
+type PostgresNotebookStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewNotebookStore(pool *pgxpool.Pool) *PostgresNotebookStore {
+	return &PostgresNotebookStore{
+		pool: pool,
+	}
+}
+
+func (s *PostgresNotebookStore) Create(ctx context.Context, notebook *models.Notebook) error {
+	query := `
+		INSERT INTO notebooks (user_id, parent_id, name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+	now := time.Now()
+	notebook.CreatedAt = now
+	notebook.UpdatedAt = now
+
+	err := s.pool.QueryRow(ctx, query,
+		notebook.UserID,
+		notebook.ParentID,
+		notebook.Name,
+		notebook.CreatedAt,
+		notebook.UpdatedAt,
+	).Scan(&notebook.ID)
+	if isUniqueViolation(err) {
+		return ErrNotebookExists
+	}
+	return err
+}
+
+func (s *PostgresNotebookStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Notebook, error) {
+	query := `
+		SELECT nb.id, nb.user_id, nb.parent_id, nb.name, nb.created_at, nb.updated_at,
+			(SELECT COUNT(*) FROM notes n WHERE n.notebook_id = nb.id AND n.deleted_at IS NULL)
+		FROM notebooks nb
+		WHERE nb.id = $1
+	`
+	var notebook models.Notebook
+	err := s.pool.QueryRow(ctx, query, id).Scan(
+		&notebook.ID,
+		&notebook.UserID,
+		&notebook.ParentID,
+		&notebook.Name,
+		&notebook.CreatedAt,
+		&notebook.UpdatedAt,
+		&notebook.NoteCount,
+	)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &notebook, nil
+}
+
+// GetAll lists every notebook of the user with the number of notes filed
+// directly in it. Parents are not guaranteed to come before their children.
+func (s *PostgresNotebookStore) GetAll(ctx context.Context, userID uuid.UUID) ([]models.Notebook, error) {
+	query := `
+		SELECT nb.id, nb.user_id, nb.parent_id, nb.name, nb.created_at, nb.updated_at, COUNT(n.id)
+		FROM notebooks nb
+		LEFT JOIN notes n ON n.notebook_id = nb.id AND n.deleted_at IS NULL
+		WHERE nb.user_id = $1
+		GROUP BY nb.id
+		ORDER BY nb.name, nb.id
+	`
+	rows, err := s.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	notebooks := []models.Notebook{}
+	for rows.Next() {
+		var notebook models.Notebook
+		err := rows.Scan(
+			&notebook.ID,
+			&notebook.UserID,
+			&notebook.ParentID,
+			&notebook.Name,
+			&notebook.CreatedAt,
+			&notebook.UpdatedAt,
+			&notebook.NoteCount,
+		)
+		if err != nil {
+			return nil, err
+		}
+		notebooks = append(notebooks, notebook)
+	}
+
+	return notebooks, rows.Err()
+}
+
+func (s *PostgresNotebookStore) Rename(ctx context.Context, notebook *models.Notebook, name string) error {
+	query := `
+		UPDATE notebooks
+		SET name = $1, updated_at = $2
+		WHERE id = $3
+		RETURNING name, updated_at
+	`
+	err := s.pool.QueryRow(ctx, query, name, time.Now(), notebook.ID).Scan(&notebook.Name, &notebook.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrNotebookExists
+	}
+	return err
+}
+
+// Move puts the notebook under parentID, or at the root when it is nil.
+// The notebooks of the user stay locked while the new parent is checked,
+// so two concurrent moves cannot build a cycle between them.
+func (s *PostgresNotebookStore) Move(ctx context.Context, notebook *models.Notebook, parentID *uuid.UUID) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM notebooks WHERE user_id = $1 FOR UPDATE`, notebook.UserID); err != nil {
+		return err
+	}
+
+	if parentID != nil {
+		var cycle bool
+		query := `SELECT $2 IN (` + notebookSubtree("$1") + `)`
+		if err := tx.QueryRow(ctx, query, notebook.ID, *parentID).Scan(&cycle); err != nil {
+			return err
+		}
+		if cycle {
+			return ErrNotebookCycle
+		}
+	}
+
+	query := `
+		UPDATE notebooks
+		SET parent_id = $1, updated_at = $2
+		WHERE id = $3
+		RETURNING parent_id, updated_at
+	`
+	err = tx.QueryRow(ctx, query, parentID, time.Now(), notebook.ID).Scan(&notebook.ParentID, &notebook.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrNotebookExists
+	}
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// Delete removes the notebook and its descendants. The notes filed in any
+// of them go to the root or, with NotebookDeleteCascade, to the trash.
+func (s *PostgresNotebookStore) Delete(ctx context.Context, id uuid.UUID, mode NotebookDeleteMode) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if mode == NotebookDeleteCascade {
+		query := `
+			UPDATE notes
+			SET deleted_at = $2
+			WHERE deleted_at IS NULL AND notebook_id IN (` + notebookSubtree("$1") + `)
+		`
+		if _, err := tx.Exec(ctx, query, id, time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// Child notebooks go with the parent and notes fall back to the root
+	// through the foreign keys.
+	if _, err := tx.Exec(ctx, `DELETE FROM notebooks WHERE id = $1`, id); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+)
+
+// ErrInvalidCursor is returned by GetAll when a cursor cannot be decoded or
+// was issued for another sort order.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Page selects the part of a listing GetAll returns. Offset pages are
+// numbered from 1 and come with the total count. Keyset pages continue
+// after the row Cursor was issued for, or start at the top when it is
+// empty, and skip counting.
+type Page struct {
+	Number int64
+	Limit  int64
+	Cursor string
+	Keyset bool
+}
+
+func (p Page) offset() int64 {
+	return (p.Number - 1) * p.Limit
+}
+
+// sortKey is one column of a keyset ordering. value renders the key of a
+// row as text and cast turns it back into the column type in SQL, so every
+// cursor value travels as a string.
+type sortKey[T any] struct {
+	expr  string
+	cast  string
+	desc  bool
+	value func(*T) string
+}
+
+// keyset is an ordering that is total thanks to the unique key it ends
+// with, which is what lets a cursor tell exactly where a page stopped.
+type keyset[T any] struct {
+	name string
+	keys []sortKey[T]
+}
+
+func (k keyset[T]) orderBy() string {
+	columns := make([]string, len(k.keys))
+	for i, key := range k.keys {
+		columns[i] = key.expr
+		if key.desc {
+			columns[i] += " DESC"
+		}
+	}
+	return strings.Join(columns, ", ")
+}
+
+// after returns the condition selecting the rows that come after the
+// cursor values, bound as consecutive parameters from firstArg. Keys sort
+// in different directions, so it cannot be a single row comparison.
+func (k keyset[T]) after(firstArg int) string {
+	terms := make([]string, len(k.keys))
+	for i, key := range k.keys {
+		conditions := make([]string, 0, i+1)
+		for j := range i {
+			conditions = append(conditions, fmt.Sprintf("%s = $%d::%s", k.keys[j].expr, firstArg+j, k.keys[j].cast))
+		}
+		op := ">"
+		if key.desc {
+			op = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("%s %s $%d::%s", key.expr, op, firstArg+i, key.cast))
+		terms[i] = "(" + strings.Join(conditions, " AND ") + ")"
+	}
+	return "(" + strings.Join(terms, " OR ") + ")"
+}
+
+type cursorToken struct {
+	Sort   string   `json:"s"`
+	Values []string `json:"v"`
+}
+
+// cursor encodes the position of row in the ordering as an opaque token.
+func (k keyset[T]) cursor(row *T) string {
+	token := cursorToken{Sort: k.name, Values: make([]string, len(k.keys))}
+	for i, key := range k.keys {
+		token.Values[i] = key.value(row)
+	}
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decode returns the values in cursor as query arguments. Values that do
+// not fit the type of their key are rejected here rather than left to fail
+// the cast in SQL.
+func (k keyset[T]) decode(cursor string) ([]any, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var token cursorToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	if token.Sort != k.name || len(token.Values) != len(k.keys) {
+		return nil, ErrInvalidCursor
+	}
+
+	args := make([]any, len(token.Values))
+	for i, value := range token.Values {
+		if !validCursorValue(k.keys[i].cast, value) {
+			return nil, ErrInvalidCursor
+		}
+		args[i] = value
+	}
+	return args, nil
+}
+
+func validCursorValue(cast, value string) bool {
+	var err error
+	switch cast {
+	case "boolean":
+		_, err = strconv.ParseBool(value)
+	case "integer":
+		_, err = strconv.ParseInt(value, 10, 32)
+	case "real":
+		_, err = strconv.ParseFloat(value, 32)
+	case "timestamp":
+		_, err = time.Parse(keysetTime, value)
+	case "uuid":
+		_, err = uuid.Parse(value)
+	}
+	return err == nil
+}
+
+// keysetMetadata trims the extra row fetched to learn whether there is a
+// next page and returns the metadata pointing at it.
+func keysetMetadata[T any](rows []T, page Page, order keyset[T]) ([]T, models.PaginationMetadata) {
+	meta := models.PaginationMetadata{Limit: page.Limit, Sort: order.name}
+	if int64(len(rows)) > page.Limit {
+		rows = rows[:page.Limit]
+		meta.HasMore = true
+		meta.NextCursor = order.cursor(&rows[len(rows)-1])
+	}
+	return rows, meta
+}
+
+func offsetMetadata(page Page, total int64, sort string) models.PaginationMetadata {
+	totalPages := (total + page.Limit - 1) / page.Limit
+	return models.PaginationMetadata{
+		Page:       page.Number,
+		Limit:      page.Limit,
+		Count:      total,
+		TotalPages: totalPages,
+		HasMore:    page.Number < totalPages,
+		Sort:       sort,
+	}
+}
@@ -0,0 +1,185 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+)
+
+func TestKeysetOrderBy(t *testing.T) {
+	tests := []struct {
+		sort NoteSort
+		want string
+	}{
+		{NoteSortUpdated, "n.pinned DESC, n.updated_at DESC, n.id DESC"},
+		{NoteSortCreated, "n.pinned DESC, n.created_at DESC, n.id DESC"},
+		{NoteSortTitle, "n.pinned DESC, n.title, n.id"},
+		{NoteSortRelevance, "rank DESC, n.updated_at DESC, n.id DESC"},
+		{NoteSortPosition, "n.pinned DESC, n.position, n.updated_at DESC, n.id"},
+		{"", "n.pinned DESC, n.position, n.updated_at DESC, n.id"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.sort), func(t *testing.T) {
+			if got := noteKeyset(tt.sort, "rank").orderBy(); got != tt.want {
+				t.Errorf("orderBy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysetAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		order    keyset[models.Note]
+		firstArg int
+		want     string
+	}{
+		{
+			name:     "descending with ties on updated_at",
+			order:    noteKeyset(NoteSortUpdated, ""),
+			firstArg: 3,
+			want: "((n.pinned < $3::boolean)" +
+				" OR (n.pinned = $3::boolean AND n.updated_at < $4::timestamp)" +
+				" OR (n.pinned = $3::boolean AND n.updated_at = $4::timestamp AND n.id < $5::uuid))",
+		},
+		{
+			name:     "ascending after a descending key",
+			order:    noteKeyset(NoteSortTitle, ""),
+			firstArg: 2,
+			want: "((n.pinned < $2::boolean)" +
+				" OR (n.pinned = $2::boolean AND n.title > $3::text)" +
+				" OR (n.pinned = $2::boolean AND n.title = $3::text AND n.id > $4::uuid))",
+		},
+		{
+			name:     "mixed directions",
+			order:    noteKeyset(NoteSortPosition, ""),
+			firstArg: 1,
+			want: "((n.pinned < $1::boolean)" +
+				" OR (n.pinned = $1::boolean AND n.position > $2::integer)" +
+				" OR (n.pinned = $1::boolean AND n.position = $2::integer AND n.updated_at < $3::timestamp)" +
+				" OR (n.pinned = $1::boolean AND n.position = $2::integer AND n.updated_at = $3::timestamp AND n.id > $4::uuid))",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.order.after(tt.firstArg); got != tt.want {
+				t.Errorf("after(%d) =\n%s\nwant\n%s", tt.firstArg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysetCursorRoundTrip(t *testing.T) {
+	updated := time.Date(2025, 3, 14, 9, 26, 53, 589793000, time.UTC)
+	first := models.Note{ID: uuid.MustParse("6f1c1e9a-5a8e-4b8e-9d44-0c3f8f1f2a01"), Title: "Budget", Pinned: true, Position: 2, UpdatedAt: updated, CreatedAt: updated, Rank: 0.25}
+	// Same updated_at as first: only the id keeps their cursors apart.
+	second := first
+	second.ID = uuid.MustParse("6f1c1e9a-5a8e-4b8e-9d44-0c3f8f1f2a02")
+
+	tests := []struct {
+		sort NoteSort
+		note models.Note
+		want []any
+	}{
+		{NoteSortUpdated, first, []any{"true", "2025-03-14T09:26:53.589793", first.ID.String()}},
+		{NoteSortUpdated, second, []any{"true", "2025-03-14T09:26:53.589793", second.ID.String()}},
+		{NoteSortCreated, first, []any{"true", "2025-03-14T09:26:53.589793", first.ID.String()}},
+		{NoteSortTitle, first, []any{"true", "Budget", first.ID.String()}},
+		{NoteSortRelevance, first, []any{"0.25", "2025-03-14T09:26:53.589793", first.ID.String()}},
+		{NoteSortPosition, first, []any{"true", "2", "2025-03-14T09:26:53.589793", first.ID.String()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.sort)+"/"+tt.note.ID.String(), func(t *testing.T) {
+			order := noteKeyset(tt.sort, "rank")
+			args, err := order.decode(order.cursor(&tt.note))
+			if err != nil {
+				t.Fatalf("decode() returned error: %v", err)
+			}
+			if !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("decode() = %v, want %v", args, tt.want)
+			}
+		})
+	}
+
+	order := noteKeyset(NoteSortUpdated, "")
+	if order.cursor(&first) == order.cursor(&second) {
+		t.Error("notes tied on updated_at got the same cursor")
+	}
+}
+
+func TestKeysetDecodeRejectsBadCursors(t *testing.T) {
+	order := noteKeyset(NoteSortUpdated, "")
+	position := noteKeyset(NoteSortPosition, "")
+	note := models.Note{ID: uuid.New(), UpdatedAt: time.Now()}
+	valid := order.cursor(&note)
+	raw := func(payload string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(payload))
+	}
+
+	tests := []struct {
+		name   string
+		order  keyset[models.Note]
+		cursor string
+	}{
+		{"empty", order, ""},
+		{"not base64", order, "not a cursor!"},
+		{"truncated", order, valid[:len(valid)-4]},
+		{"not json", order, raw("updated|true|2025-01-01")},
+		{"json of the wrong shape", order, raw(`{"s":"updated","v":"true"}`)},
+		{"other sort order", order, noteKeyset(NoteSortCreated, "").cursor(&note)},
+		{"missing sort", order, raw(`{"v":["true","2025-01-01T00:00:00","` + note.ID.String() + `"]}`)},
+		{"too few values", order, raw(`{"s":"updated","v":["true","2025-01-01T00:00:00"]}`)},
+		{"too many values", order, raw(`{"s":"updated","v":["true","2025-01-01T00:00:00","a","b"]}`)},
+		{"bad boolean", order, raw(`{"s":"updated","v":["yes","2025-01-01T00:00:00","` + note.ID.String() + `"]}`)},
+		{"bad timestamp", order, raw(`{"s":"updated","v":["true","2025-01-01 00:00","` + note.ID.String() + `"]}`)},
+		{"bad uuid", order, raw(`{"s":"updated","v":["true","2025-01-01T00:00:00","1 OR 1=1"]}`)},
+		{"bad integer", position, raw(`{"s":"position","v":["false","1.5","2025-01-01T00:00:00","` + note.ID.String() + `"]}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := tt.order.decode(tt.cursor)
+			if !errors.Is(err, ErrInvalidCursor) {
+				t.Errorf("decode(%q) = %v, %v, want ErrInvalidCursor", tt.cursor, args, err)
+			}
+		})
+	}
+}
+
+func TestKeysetMetadata(t *testing.T) {
+	order := tagKeyset(TagSortName)
+	tags := []models.Tag{{ID: uuid.New(), Name: "a"}, {ID: uuid.New(), Name: "b"}, {ID: uuid.New(), Name: "c"}}
+
+	tests := []struct {
+		name       string
+		rows       int
+		wantRows   int
+		wantMore   bool
+		wantCursor string
+	}{
+		{"last page", 2, 2, false, ""},
+		{"extra row", 3, 2, true, order.cursor(&tags[1])},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, meta := keysetMetadata(tags[:tt.rows], Page{Limit: 2, Keyset: true}, order)
+			if len(rows) != tt.wantRows {
+				t.Errorf("got %d rows, want %d", len(rows), tt.wantRows)
+			}
+			want := models.PaginationMetadata{Limit: 2, Sort: "name", HasMore: tt.wantMore, NextCursor: tt.wantCursor}
+			if meta != want {
+				t.Errorf("metadata = %+v, want %+v", meta, want)
+			}
+		})
+	}
+}
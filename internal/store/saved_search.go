@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+)
+
+// ErrSavedSearchExists is returned when the user already has a saved search
+// with the same name.
+var ErrSavedSearchExists = errors.New("saved search already exists")
+
+const savedSearchColumns = `id, user_id, name, search, tags, tag_match, sort, created_at, updated_at`
+
+func savedSearchScanFields(search *models.SavedSearch) []any {
+	return []any{
+		&search.ID,
+		&search.UserID,
+		&search.Name,
+		&search.Search,
+		&search.Tags,
+		&search.TagMatch,
+		&search.Sort,
+		&search.CreatedAt,
+		&search.UpdatedAt,
+	}
+}
+
+type PostgresSavedSearchStore struct {
+	pool *pgxpool.Pool
+}
+
+func NewSavedSearchStore(pool *pgxpool.Pool) *PostgresSavedSearchStore {
+	return &PostgresSavedSearchStore{
+		pool: pool,
+	}
+}
+
+func (s *PostgresSavedSearchStore) Create(ctx context.Context, search *models.SavedSearch) error {
+	query := `
+		INSERT INTO saved_searches (user_id, name, search, tags, tag_match, sort, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+	now := time.Now()
+	search.CreatedAt = now
+	search.UpdatedAt = now
+	if search.Tags == nil {
+		search.Tags = []string{}
+	}
+
+	err := s.pool.QueryRow(ctx, query,
+		search.UserID,
+		search.Name,
+		search.Search,
+		search.Tags,
+		search.TagMatch,
+		search.Sort,
+		search.CreatedAt,
+		search.UpdatedAt,
+	).Scan(&search.ID)
+	if isUniqueViolation(err) {
+		return ErrSavedSearchExists
+	}
+	return err
+}
+
+func (s *PostgresSavedSearchStore) GetByID(ctx context.Context, id uuid.UUID) (*models.SavedSearch, error) {
+	query := `SELECT ` + savedSearchColumns + ` FROM saved_searches WHERE id = $1`
+
+	var search models.SavedSearch
+	err := s.pool.QueryRow(ctx, query, id).Scan(savedSearchScanFields(&search)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &search, nil
+}
+
+// GetAll lists the saved searches of the user by name.
+func (s *PostgresSavedSearchStore) GetAll(ctx context.Context, userID uuid.UUID) ([]models.SavedSearch, error) {
+	query := `
+		SELECT ` + savedSearchColumns + `
+		FROM saved_searches
+		WHERE user_id = $1
+		ORDER BY name, id
+	`
+	rows, err := s.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	searches := []models.SavedSearch{}
+	for rows.Next() {
+		var search models.SavedSearch
+		if err := rows.Scan(savedSearchScanFields(&search)...); err != nil {
+			return nil, err
+		}
+		searches = append(searches, search)
+	}
+
+	return searches, rows.Err()
+}
+
+// Update stores every field of the saved search but its owner.
+func (s *PostgresSavedSearchStore) Update(ctx context.Context, search *models.SavedSearch) error {
+	query := `
+		UPDATE saved_searches
+		SET name = $1, search = $2, tags = $3, tag_match = $4, sort = $5, updated_at = $6
+		WHERE id = $7
+		RETURNING updated_at
+	`
+	if search.Tags == nil {
+		search.Tags = []string{}
+	}
+
+	err := s.pool.QueryRow(ctx, query,
+		search.Name,
+		search.Search,
+		search.Tags,
+		search.TagMatch,
+		search.Sort,
+		time.Now(),
+		search.ID,
+	).Scan(&search.UpdatedAt)
+	if isUniqueViolation(err) {
+		return ErrSavedSearchExists
+	}
+	return err
+}
+
+func (s *PostgresSavedSearchStore) Delete(ctx context.Context, id uuid.UUID) error {
+	_, err := s.pool.Exec(ctx, `DELETE FROM saved_searches WHERE id = $1`, id)
+	return err
+}
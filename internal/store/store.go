@@ -3,15 +3,21 @@ package store
 import "github.com/jackc/pgx/v5/pgxpool"
 
 type Storage struct {
-	Users UserStorage
-	Notes NoteStorage
-	Tags  TagStorage
+	Users         UserStorage
+	Notes         NoteStorage
+	Tags          TagStorage
+	Notebooks     NotebookStorage
+	SavedSearches SavedSearchStorage
+	Templates     NoteTemplateStorage
 }
 
 func NewStorage(pool *pgxpool.Pool) *Storage {
 	return &Storage{
-		Users: NewUserStore(pool),
-		Notes: NewNoteStore(pool),
-		Tags:  NewTagStore(pool),
+		Users:         NewUserStore(pool),
+		Notes:         NewNoteStore(pool),
+		Tags:          NewTagStore(pool),
+		Notebooks:     NewNotebookStore(pool),
+		SavedSearches: NewSavedSearchStore(pool),
+		Templates:     NewNoteTemplateStore(pool),
 	}
 }
@@ -4,14 +4,85 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/manuelmtzv/mangocatnotes-api/internal/models"
+	"github.com/manuelmtzv/mangocatnotes-api/internal/tagcolor"
 )
 
+// ErrTagCycle is returned by Patch and Merge when a tag would end up below
+// itself.
+var ErrTagCycle = errors.New("tag cannot be moved under itself")
+
+// ErrTagExists is returned when another tag of the user already has the
+// name.
+var ErrTagExists = errors.New("tag already exists")
+
+// ErrTagHasChildren is returned when deleting a tag that other tags are
+// nested under.
+var ErrTagHasChildren = errors.New("tag has child tags, delete or move them first")
+
+const tagColumns = `t.id, t.user_id, t.parent_id, t.name, t.color, t.created_at, t.updated_at`
+
+// tagScanFields returns the scan destinations matching tagColumns.
+func tagScanFields(tag *models.Tag) []any {
+	return []any{
+		&tag.ID,
+		&tag.UserID,
+		&tag.ParentID,
+		&tag.Name,
+		&tag.Color,
+		&tag.CreatedAt,
+		&tag.UpdatedAt,
+	}
+}
+
+// TagPath cleans up a tag name written as a /-separated path, trimming the
+// segments and dropping empty ones, so "work / clients/" is "work/clients".
+func TagPath(name string) string {
+	var segments []string
+	for _, segment := range strings.Split(name, "/") {
+		if segment = strings.TrimSpace(segment); segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+	return strings.Join(segments, "/")
+}
+
+// tagAncestors returns the paths above path, outermost first.
+func tagAncestors(path string) []string {
+	var ancestors []string
+	for i, char := range path {
+		if char == '/' {
+			ancestors = append(ancestors, path[:i])
+		}
+	}
+	return ancestors
+}
+
+// tagPathMatch returns a condition that holds when the tag name in column is
+// path or lies anywhere below it.
+func tagPathMatch(column, path string) string {
+	return fmt.Sprintf("(%[1]s = %[2]s OR left(%[1]s, length(%[2]s) + 1) = %[2]s || '/')", column, path)
+}
+
+// tagUsageJoin adds u.note_count and u.last_used_at for the tag t. Only
+// notes outside the trash count, and the last use is when the tag was last
+// attached to one of them.
+const tagUsageJoin = `
+	LEFT JOIN LATERAL (
+		SELECT COUNT(n.id)::integer AS note_count, MAX(nt.created_at) AS last_used_at
+		FROM note_tags nt
+		JOIN notes n ON n.id = nt.note_id AND n.deleted_at IS NULL
+		WHERE nt.tag_id = t.id
+	) u ON TRUE
+`
+
 type PostgresTagStore struct {
 	pool *pgxpool.Pool
 }
@@ -22,42 +93,55 @@ func NewTagStore(pool *pgxpool.Pool) *PostgresTagStore {
 	}
 }
 
+// Create saves a new tag, creating the ancestors its path names that do not
+// exist yet.
 func (s *PostgresTagStore) Create(ctx context.Context, tag *models.Tag) error {
-	query := `
-		INSERT INTO tags (user_id, name, color, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING id
-	`
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
 	now := time.Now()
+	tag.Name = TagPath(tag.Name)
+	if tag.Color == "" {
+		tag.Color = tagcolor.ForName(tag.Name)
+	}
 	tag.CreatedAt = now
 	tag.UpdatedAt = now
 
-	err := s.pool.QueryRow(ctx, query,
+	if tag.ParentID, err = ensureTagAncestors(ctx, tx, tag.UserID, tag.Name, now); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tags (user_id, parent_id, name, color, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id
+	`
+	err = tx.QueryRow(ctx, query,
 		tag.UserID,
+		tag.ParentID,
 		tag.Name,
 		tag.Color,
 		tag.CreatedAt,
 		tag.UpdatedAt,
 	).Scan(&tag.ID)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return tx.Commit(ctx)
 }
 
 func (s *PostgresTagStore) GetByID(ctx context.Context, id uuid.UUID) (*models.Tag, error) {
 	query := `
-		SELECT id, user_id, name, color, created_at, updated_at
-		FROM tags
-		WHERE id = $1
+		SELECT ` + tagColumns + `
+		FROM tags t
+		WHERE t.id = $1
 	`
 	var tag models.Tag
-	err := s.pool.QueryRow(ctx, query, id).Scan(
-		&tag.ID,
-		&tag.UserID,
-		&tag.Name,
-		&tag.Color,
-		&tag.CreatedAt,
-		&tag.UpdatedAt,
-	)
+	err := s.pool.QueryRow(ctx, query, id).Scan(tagScanFields(&tag)...)
 
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
@@ -68,80 +152,418 @@ func (s *PostgresTagStore) GetByID(ctx context.Context, id uuid.UUID) (*models.T
 	return &tag, nil
 }
 
-func (s *PostgresTagStore) GetAll(ctx context.Context, userID uuid.UUID, page, limit int64) ([]models.Tag, int64, error) {
-	offset := (page - 1) * limit
-
-	countQuery := `
-		SELECT COUNT(*)
-		FROM tags
-		WHERE user_id = $1
+// GetByName returns the tag of the user at the path name, or nil.
+func (s *PostgresTagStore) GetByName(ctx context.Context, userID uuid.UUID, name string) (*models.Tag, error) {
+	query := `
+		SELECT ` + tagColumns + `
+		FROM tags t
+		WHERE t.user_id = $1 AND t.name = $2
 	`
-	var total int64
-	err := s.pool.QueryRow(ctx, countQuery, userID).Scan(&total)
+	var tag models.Tag
+	err := s.pool.QueryRow(ctx, query, userID, TagPath(name)).Scan(tagScanFields(&tag)...)
+
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
+	return &tag, nil
+}
 
-	dataQuery := `
-		SELECT id, user_id, name, color, created_at, updated_at
-		FROM tags
-		WHERE user_id = $1
-		ORDER BY name
-		LIMIT $2 OFFSET $3
-	`
+// TagSort orders the tags returned by GetAll.
+type TagSort string
+
+const (
+	TagSortName       TagSort = "name"
+	TagSortCreated    TagSort = "created"
+	TagSortUpdated    TagSort = "updated"
+	TagSortPopularity TagSort = "popularity"
+)
+
+// TagFilter shapes the tags returned by GetAll. Usage fills in the note
+// count and last use of every tag; sorting by popularity implies it.
+type TagFilter struct {
+	Sort  TagSort
+	Usage bool
+}
+
+func tagKeyset(sort TagSort) keyset[models.Tag] {
+	id := func(desc bool) sortKey[models.Tag] {
+		return sortKey[models.Tag]{"t.id", "uuid", desc, func(t *models.Tag) string { return t.ID.String() }}
+	}
+
+	switch sort {
+	case TagSortCreated:
+		created := sortKey[models.Tag]{"t.created_at", "timestamp", true, func(t *models.Tag) string { return t.CreatedAt.Format(keysetTime) }}
+		return keyset[models.Tag]{string(sort), []sortKey[models.Tag]{created, id(true)}}
+	case TagSortUpdated:
+		updated := sortKey[models.Tag]{"t.updated_at", "timestamp", true, func(t *models.Tag) string { return t.UpdatedAt.Format(keysetTime) }}
+		return keyset[models.Tag]{string(sort), []sortKey[models.Tag]{updated, id(true)}}
+	}
 
-	rows, err := s.pool.Query(ctx, dataQuery, userID, limit, offset)
+	name := sortKey[models.Tag]{"t.name", "text", false, func(t *models.Tag) string { return t.Name }}
+	if sort == TagSortPopularity {
+		count := sortKey[models.Tag]{"u.note_count", "integer", true, func(t *models.Tag) string { return strconv.Itoa(*t.NoteCount) }}
+		return keyset[models.Tag]{string(sort), []sortKey[models.Tag]{count, name, id(false)}}
+	}
+	return keyset[models.Tag]{string(TagSortName), []sortKey[models.Tag]{name, id(false)}}
+}
+
+func (s *PostgresTagStore) GetAll(ctx context.Context, userID uuid.UUID, page Page, filter TagFilter) ([]models.Tag, models.PaginationMetadata, error) {
+	order := tagKeyset(filter.Sort)
+	usage := filter.Usage || filter.Sort == TagSortPopularity
+
+	var total int64
+	if !page.Keyset {
+		countQuery := `
+			SELECT COUNT(*)
+			FROM tags
+			WHERE user_id = $1
+		`
+		if err := s.pool.QueryRow(ctx, countQuery, userID).Scan(&total); err != nil {
+			return nil, models.PaginationMetadata{}, err
+		}
+	}
+
+	where := "t.user_id = $1"
+	args := []any{userID}
+	limit := page.Limit
+	if page.Keyset {
+		if page.Cursor != "" {
+			cursorArgs, err := order.decode(page.Cursor)
+			if err != nil {
+				return nil, models.PaginationMetadata{}, err
+			}
+			where += " AND " + order.after(2)
+			args = append(args, cursorArgs...)
+		}
+		limit++
+	}
+
+	columns, join := tagColumns, ""
+	if usage {
+		columns, join = tagColumns+", u.note_count, u.last_used_at", tagUsageJoin
+	}
+
+	dataQuery := fmt.Sprintf(`
+		SELECT %s
+		FROM tags t
+		%s
+		WHERE %s
+		ORDER BY %s
+		LIMIT $%d
+	`, columns, join, where, order.orderBy(), len(args)+1)
+	args = append(args, limit)
+	if !page.Keyset {
+		dataQuery += fmt.Sprintf(" OFFSET $%d", len(args)+1)
+		args = append(args, page.offset())
+	}
+
+	rows, err := s.pool.Query(ctx, dataQuery, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, models.PaginationMetadata{}, err
 	}
 	defer rows.Close()
 
 	var tags []models.Tag
 	for rows.Next() {
 		var tag models.Tag
-		err := rows.Scan(
-			&tag.ID,
-			&tag.UserID,
-			&tag.Name,
-			&tag.Color,
-			&tag.CreatedAt,
-			&tag.UpdatedAt,
-		)
-		if err != nil {
-			return nil, 0, err
+		fields := tagScanFields(&tag)
+		if usage {
+			fields = append(fields, &tag.NoteCount, &tag.LastUsedAt)
+		}
+		if err := rows.Scan(fields...); err != nil {
+			return nil, models.PaginationMetadata{}, err
 		}
 		tags = append(tags, tag)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, 0, err
+		return nil, models.PaginationMetadata{}, err
+	}
+
+	if page.Keyset {
+		tags, meta := keysetMetadata(tags, page, order)
+		return tags, meta, nil
+	}
+	return tags, offsetMetadata(page, total, order.name), nil
+}
+
+// GetTree returns the tags of the user as a tree sorted by name, with the
+// usage of every tag and the number of notes outside the trash under it.
+func (s *PostgresTagStore) GetTree(ctx context.Context, userID uuid.UUID) ([]models.TagNode, error) {
+	query := `
+		SELECT ` + tagColumns + `, u.note_count, u.last_used_at,
+			(
+				SELECT COUNT(DISTINCT nt.note_id)
+				FROM tags d
+				JOIN note_tags nt ON nt.tag_id = d.id
+				JOIN notes n ON n.id = nt.note_id AND n.deleted_at IS NULL
+				WHERE d.user_id = t.user_id AND ` + tagPathMatch("d.name", "t.name") + `
+			)
+		FROM tags t
+		` + tagUsageJoin + `
+		WHERE t.user_id = $1
+		ORDER BY t.name
+	`
+	rows, err := s.pool.Query(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nodes []models.TagNode
+	for rows.Next() {
+		var node models.TagNode
+		if err := rows.Scan(append(tagScanFields(&node.Tag), &node.NoteCount, &node.LastUsedAt, &node.TotalCount)...); err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	children := make(map[uuid.UUID][]int)
+	var roots []int
+	for i, node := range nodes {
+		if node.ParentID == nil {
+			roots = append(roots, i)
+		} else {
+			children[*node.ParentID] = append(children[*node.ParentID], i)
+		}
+	}
+
+	var build func(indexes []int) []models.TagNode
+	build = func(indexes []int) []models.TagNode {
+		level := make([]models.TagNode, 0, len(indexes))
+		for _, i := range indexes {
+			node := nodes[i]
+			node.Children = build(children[node.ID])
+			level = append(level, node)
+		}
+		return level
 	}
 
-	return tags, total, nil
+	return build(roots), nil
+}
+
+// TagPatch lists the tag fields to change. Nil fields keep their current
+// value.
+type TagPatch struct {
+	Name  *string
+	Color *string
 }
 
+// Update saves every editable field of the tag. See Patch.
 func (s *PostgresTagStore) Update(ctx context.Context, tag *models.Tag) error {
-	tag.UpdatedAt = time.Now()
+	return s.Patch(ctx, tag, TagPatch{
+		Name:  &tag.Name,
+		Color: &tag.Color,
+	})
+}
+
+// Patch changes the fields set in patch and reloads tag with the result. A
+// new name can move the tag to another place in the tree: its parent is
+// found or created, and its descendants follow it to the new path.
+func (s *PostgresTagStore) Patch(ctx context.Context, tag *models.Tag, patch TagPatch) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	oldName := tag.Name
+
+	var sets []string
+	var args []any
+	set := func(column string, value any) {
+		args = append(args, value)
+		sets = append(sets, fmt.Sprintf("%s = $%d", column, len(args)))
+	}
+
+	renamed := false
+	if patch.Name != nil {
+		name := TagPath(*patch.Name)
+		if strings.HasPrefix(name, oldName+"/") {
+			return ErrTagCycle
+		}
+		if name != oldName {
+			parentID, err := ensureTagAncestors(ctx, tx, tag.UserID, name, now)
+			if err != nil {
+				return err
+			}
+			set("name", name)
+			set("parent_id", parentID)
+			renamed = true
+		}
+	}
+	if patch.Color != nil {
+		set("color", *patch.Color)
+	}
+	set("updated_at", now)
+
+	args = append(args, tag.ID)
+	query := fmt.Sprintf(`
+		UPDATE tags t
+		SET %s
+		WHERE t.id = $%d
+		RETURNING %s
+	`, strings.Join(sets, ", "), len(args), tagColumns)
+
+	if err := tx.QueryRow(ctx, query, args...).Scan(tagScanFields(tag)...); err != nil {
+		if isUniqueViolation(err) {
+			return ErrTagExists
+		}
+		return err
+	}
+
+	if renamed {
+		if err := renameTagDescendants(ctx, tx, tag.UserID, oldName, tag.Name, now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// renameTagDescendants moves the tags below oldName to the same place below
+// newName.
+func renameTagDescendants(ctx context.Context, tx pgx.Tx, userID uuid.UUID, oldName, newName string, now time.Time) error {
 	query := `
 		UPDATE tags
-		SET name = $1, color = $2, updated_at = $3
-		WHERE id = $4
+		SET name = $3 || substr(name, length($2) + 1), updated_at = $4
+		WHERE user_id = $1 AND left(name, length($2) + 1) = $2 || '/'
 	`
-	_, err := s.pool.Exec(ctx, query,
-		tag.Name,
-		tag.Color,
-		tag.UpdatedAt,
-		tag.ID,
-	)
+	_, err := tx.Exec(ctx, query, userID, oldName, newName, now)
+	return err
+}
+
+// Merge moves the notes of source onto target and deletes source, all in one
+// transaction. Notes that already carry target keep a single association.
+// The tags below source move below target, and those whose new path is
+// taken are merged into the tag there the same way. target is reloaded.
+func (s *PostgresTagStore) Merge(ctx context.Context, source, target *models.Tag) error {
+	if source.ID == target.ID || strings.HasPrefix(target.Name, source.Name+"/") {
+		return ErrTagCycle
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	// The tags of the user stay locked so no tag appears in the paths being
+	// rewritten halfway through.
+	if _, err := tx.Exec(ctx, `SELECT 1 FROM tags WHERE user_id = $1 FOR UPDATE`, source.UserID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := mergeTag(ctx, tx, source.UserID, source.ID, source.Name, target.ID, target.Name, now); err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE tags t
+		SET updated_at = $2
+		WHERE t.id = $1
+		RETURNING ` + tagColumns
+	if err := tx.QueryRow(ctx, query, target.ID, now).Scan(tagScanFields(target)...); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+func mergeTag(ctx context.Context, tx pgx.Tx, userID, sourceID uuid.UUID, sourceName string, targetID uuid.UUID, targetName string, now time.Time) error {
+	query := `
+		INSERT INTO note_tags (note_id, tag_id, created_at)
+		SELECT note_id, $2, created_at
+		FROM note_tags
+		WHERE tag_id = $1
+		ON CONFLICT (note_id, tag_id) DO NOTHING
+	`
+	if _, err := tx.Exec(ctx, query, sourceID, targetID); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx, `SELECT t.id, t.name FROM tags t WHERE t.parent_id = $1 ORDER BY t.name`, sourceID)
+	if err != nil {
+		return err
+	}
+	type child struct {
+		ID   uuid.UUID
+		Name string
+	}
+	children, err := pgx.CollectRows(rows, pgx.RowToStructByPos[child])
+	if err != nil {
+		return err
+	}
+
+	for _, c := range children {
+		path := targetName + strings.TrimPrefix(c.Name, sourceName)
+
+		var existingID uuid.UUID
+		err := tx.QueryRow(ctx, `SELECT id FROM tags WHERE user_id = $1 AND name = $2`, userID, path).Scan(&existingID)
+		switch {
+		case err == nil:
+			if err := mergeTag(ctx, tx, userID, c.ID, c.Name, existingID, path, now); err != nil {
+				return err
+			}
+		case errors.Is(err, pgx.ErrNoRows):
+			query := `UPDATE tags SET parent_id = $2, name = $3, updated_at = $4 WHERE id = $1`
+			if _, err := tx.Exec(ctx, query, c.ID, targetID, path, now); err != nil {
+				return err
+			}
+			if err := renameTagDescendants(ctx, tx, userID, c.Name, path, now); err != nil {
+				return err
+			}
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.Exec(ctx, `DELETE FROM tags WHERE id = $1`, sourceID)
 	return err
 }
 
+// Delete removes the tag, which must not have any tags below it.
 func (s *PostgresTagStore) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM tags WHERE id = $1`
 	_, err := s.pool.Exec(ctx, query, id)
+	if isForeignKeyViolation(err) {
+		return ErrTagHasChildren
+	}
 	return err
 }
 
+// DeleteUnused removes the tags of the user that neither they nor any tag
+// below them are attached to a note, including notes in the trash so that
+// restoring one brings its tags back. It returns how many tags went.
+func (s *PostgresTagStore) DeleteUnused(ctx context.Context, userID uuid.UUID) (int64, error) {
+	query := `
+		DELETE FROM tags t
+		WHERE t.user_id = $1
+		AND NOT EXISTS (
+			SELECT 1
+			FROM tags d
+			JOIN note_tags nt ON nt.tag_id = d.id
+			WHERE d.user_id = t.user_id AND ` + tagPathMatch("d.name", "t.name") + `
+		)
+	`
+	result, err := s.pool.Exec(ctx, query, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
+}
+
+// FindOrCreate returns the tags with the given path names, creating them and
+// any missing ancestors. Names that are empty once cleaned up are skipped.
 func (s *PostgresTagStore) FindOrCreate(ctx context.Context, userID uuid.UUID, names []string) ([]models.Tag, error) {
 	if len(names) == 0 {
 		return []models.Tag{}, nil
@@ -153,35 +575,69 @@ func (s *PostgresTagStore) FindOrCreate(ctx context.Context, userID uuid.UUID, n
 	}
 	defer tx.Rollback(ctx)
 
-	var tags []models.Tag
-	now := time.Now()
+	tags, err := findOrCreateTags(ctx, tx, userID, names, time.Now())
+	if err != nil {
+		return nil, err
+	}
 
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	return tags, nil
+}
+
+// findOrCreateTags does the work of FindOrCreate inside tx.
+func findOrCreateTags(ctx context.Context, tx pgx.Tx, userID uuid.UUID, names []string, now time.Time) ([]models.Tag, error) {
+	var tags []models.Tag
 	for _, name := range names {
-		query := `
-			INSERT INTO tags (user_id, name, color, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5)
-			ON CONFLICT (user_id, name) DO UPDATE SET updated_at = EXCLUDED.updated_at
-			RETURNING id, user_id, name, color, created_at, updated_at
-		`
+		path := TagPath(name)
+		if path == "" {
+			continue
+		}
+		parentID, err := ensureTagAncestors(ctx, tx, userID, path, now)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find or create tag %s: %w", name, err)
+		}
 
-		var tag models.Tag
-		err := tx.QueryRow(ctx, query, userID, name, "#fff", now, now).Scan(
-			&tag.ID,
-			&tag.UserID,
-			&tag.Name,
-			&tag.Color,
-			&tag.CreatedAt,
-			&tag.UpdatedAt,
-		)
+		tag, err := upsertTag(ctx, tx, userID, parentID, path, now, true)
 		if err != nil {
 			return nil, fmt.Errorf("failed to find or create tag %s: %w", name, err)
 		}
 		tags = append(tags, tag)
 	}
+	return tags, nil
+}
 
-	if err := tx.Commit(ctx); err != nil {
-		return nil, err
+// ensureTagAncestors finds or creates the tags above path and returns the id
+// of its parent, or nil for a top-level path.
+func ensureTagAncestors(ctx context.Context, tx pgx.Tx, userID uuid.UUID, path string, now time.Time) (*uuid.UUID, error) {
+	var parentID *uuid.UUID
+	for _, ancestor := range tagAncestors(path) {
+		tag, err := upsertTag(ctx, tx, userID, parentID, ancestor, now, false)
+		if err != nil {
+			return nil, err
+		}
+		parentID = &tag.ID
 	}
+	return parentID, nil
+}
 
-	return tags, nil
+// upsertTag finds or creates one tag, linking it to parentID either way.
+// touch also bumps the update time of an existing tag. A new tag gets the
+// palette color of its name.
+func upsertTag(ctx context.Context, tx pgx.Tx, userID uuid.UUID, parentID *uuid.UUID, name string, now time.Time, touch bool) (models.Tag, error) {
+	updatedAt := "t.updated_at"
+	if touch {
+		updatedAt = "EXCLUDED.updated_at"
+	}
+	query := `
+		INSERT INTO tags AS t (user_id, parent_id, name, color, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, name) DO UPDATE SET parent_id = EXCLUDED.parent_id, updated_at = ` + updatedAt + `
+		RETURNING ` + tagColumns
+
+	var tag models.Tag
+	err := tx.QueryRow(ctx, query, userID, parentID, name, tagcolor.ForName(name), now, now).Scan(tagScanFields(&tag)...)
+	return tag, err
 }
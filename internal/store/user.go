@@ -25,7 +25,7 @@ func (s *PostgresUserStore) Create(ctx context.Context, user *models.User) error
 	query := `
 		INSERT INTO users (email, username, hash, name, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id
+		RETURNING id, revision_retention, timezone
 	`
 	now := time.Now()
 	user.CreatedAt = now
@@ -38,54 +38,36 @@ func (s *PostgresUserStore) Create(ctx context.Context, user *models.User) error
 		user.Name,
 		user.CreatedAt,
 		user.UpdatedAt,
-	).Scan(&user.ID)
+	).Scan(&user.ID, &user.RevisionRetention, &user.Timezone)
 
 	return err
 }
 
-func (s *PostgresUserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
-	query := `
-		SELECT id, email, username, hash, name, created_at, updated_at
-		FROM users
-		WHERE email = $1
-	`
-	var user models.User
-	err := s.pool.QueryRow(ctx, query, email).Scan(
+const userColumns = `id, email, username, hash, name, revision_retention, daily_template_id, timezone, calendar_token_hash, created_at, updated_at`
+
+// userScanFields returns the scan destinations matching userColumns.
+func userScanFields(user *models.User) []any {
+	return []any{
 		&user.ID,
 		&user.Email,
 		&user.Username,
 		&user.Hash,
 		&user.Name,
+		&user.RevisionRetention,
+		&user.DailyTemplateID,
+		&user.Timezone,
+		&user.CalendarTokenHash,
 		&user.CreatedAt,
 		&user.UpdatedAt,
-	)
-
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
 	}
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
 }
 
-func (s *PostgresUserStore) GetByUsername(ctx context.Context, username string) (*models.User, error) {
-	query := `
-		SELECT id, email, username, hash, name, created_at, updated_at
-		FROM users
-		WHERE username = $1
-	`
-	var user models.User
-	err := s.pool.QueryRow(ctx, query, username).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Username,
-		&user.Hash,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+// getWhere returns the user matching condition, which refers to arg as $1.
+func (s *PostgresUserStore) getWhere(ctx context.Context, condition string, arg any) (*models.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE ` + condition
 
+	var user models.User
+	err := s.pool.QueryRow(ctx, query, arg).Scan(userScanFields(&user)...)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	}
@@ -95,75 +77,56 @@ func (s *PostgresUserStore) GetByUsername(ctx context.Context, username string)
 	return &user, nil
 }
 
-func (s *PostgresUserStore) GetByEmailOrUsername(ctx context.Context, identifier string) (*models.User, error) {
-	query := `
-		SELECT id, email, username, hash, name, created_at, updated_at
-		FROM users
-		WHERE email = $1 OR username = $1
-	`
-	var user models.User
-	err := s.pool.QueryRow(ctx, query, identifier).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Username,
-		&user.Hash,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+// GetByCalendarTokenHash returns the user whose calendar feed token hashes
+// to hash, or nil when no feed uses it.
+func (s *PostgresUserStore) GetByCalendarTokenHash(ctx context.Context, hash string) (*models.User, error) {
+	return s.getWhere(ctx, `calendar_token_hash = $1`, hash)
+}
 
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
+func (s *PostgresUserStore) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return s.getWhere(ctx, `email = $1`, email)
 }
 
-func (s *PostgresUserStore) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
-	query := `
-		SELECT id, email, username, hash, name, created_at, updated_at
-		FROM users
-		WHERE id = $1
-	`
-	var user models.User
-	err := s.pool.QueryRow(ctx, query, id).Scan(
-		&user.ID,
-		&user.Email,
-		&user.Username,
-		&user.Hash,
-		&user.Name,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+func (s *PostgresUserStore) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.getWhere(ctx, `username = $1`, username)
+}
 
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
-	}
-	if err != nil {
-		return nil, err
-	}
-	return &user, nil
+func (s *PostgresUserStore) GetByEmailOrUsername(ctx context.Context, identifier string) (*models.User, error) {
+	return s.getWhere(ctx, `email = $1 OR username = $1`, identifier)
+}
+
+func (s *PostgresUserStore) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return s.getWhere(ctx, `id = $1`, id)
 }
 
 func (s *PostgresUserStore) Update(ctx context.Context, user *models.User) error {
 	user.UpdatedAt = time.Now()
 	query := `
 		UPDATE users
-		SET email = $1, username = $2, name = $3, updated_at = $4
-		WHERE id = $5
+		SET email = $1, username = $2, name = $3, revision_retention = $4, daily_template_id = $5, timezone = $6, updated_at = $7
+		WHERE id = $8
 	`
 	_, err := s.pool.Exec(ctx, query,
 		user.Email,
 		user.Username,
 		user.Name,
+		user.RevisionRetention,
+		user.DailyTemplateID,
+		user.Timezone,
 		user.UpdatedAt,
 		user.ID,
 	)
 	return err
 }
 
+// SetCalendarTokenHash replaces the calendar feed token of the user, which
+// revokes the previous one. A nil hash turns the feed off.
+func (s *PostgresUserStore) SetCalendarTokenHash(ctx context.Context, id uuid.UUID, hash *string) error {
+	query := `UPDATE users SET calendar_token_hash = $1 WHERE id = $2`
+	_, err := s.pool.Exec(ctx, query, hash, id)
+	return err
+}
+
 func (s *PostgresUserStore) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM users WHERE id = $1`
 	_, err := s.pool.Exec(ctx, query, id)
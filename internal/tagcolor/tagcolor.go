@@ -0,0 +1,103 @@
+package tagcolor
+
+import (
+	"hash/fnv"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Swatch is a named entry of the palette.
+type Swatch struct {
+	Name string `json:"name"`
+	Hex  string `json:"hex"`
+}
+
+// Palette lists the named colors tags can use instead of a hex value, and
+// the colors given to tags created without one.
+var Palette = []Swatch{
+	{"red", "#ef4444"},
+	{"orange", "#f97316"},
+	{"amber", "#f59e0b"},
+	{"yellow", "#eab308"},
+	{"lime", "#84cc16"},
+	{"green", "#22c55e"},
+	{"teal", "#14b8a6"},
+	{"cyan", "#06b6d4"},
+	{"blue", "#3b82f6"},
+	{"indigo", "#6366f1"},
+	{"violet", "#8b5cf6"},
+	{"pink", "#ec4899"},
+	{"slate", "#64748b"},
+}
+
+// Normalize returns color as a lowercase #rrggbb value. It accepts #rgb and
+// #rrggbb hex values and the names in Palette, and reports false for
+// anything else.
+func Normalize(color string) (string, bool) {
+	color = strings.ToLower(strings.TrimSpace(color))
+
+	for _, swatch := range Palette {
+		if color == swatch.Name {
+			return swatch.Hex, true
+		}
+	}
+
+	if !strings.HasPrefix(color, "#") {
+		return "", false
+	}
+	digits := color[1:]
+	if len(digits) != 3 && len(digits) != 6 {
+		return "", false
+	}
+	if _, err := strconv.ParseUint(digits, 16, 32); err != nil {
+		return "", false
+	}
+	if len(digits) == 3 {
+		digits = string([]byte{digits[0], digits[0], digits[1], digits[1], digits[2], digits[2]})
+	}
+	return "#" + digits, true
+}
+
+// Valid reports whether Normalize accepts color.
+func Valid(color string) bool {
+	_, ok := Normalize(color)
+	return ok
+}
+
+// ForName picks a palette color for a tag from a hash of its name, so the
+// same name always gets the same color.
+func ForName(name string) string {
+	h := fnv.New32a()
+	h.Write([]byte(strings.ToLower(name)))
+	return Palette[h.Sum32()%uint32(len(Palette))].Hex
+}
+
+// TextColor returns black or white, whichever contrasts more with the
+// background color, following the WCAG relative luminance formula. Colors
+// Normalize rejects are treated as the dark background of the app.
+func TextColor(background string) string {
+	hex, ok := Normalize(background)
+	if !ok {
+		return "#ffffff"
+	}
+
+	var luminance float64
+	for i, weight := range []float64{0.2126, 0.7152, 0.0722} {
+		value, _ := strconv.ParseUint(hex[1+i*2:3+i*2], 16, 8)
+		channel := float64(value) / 255
+		if channel <= 0.03928 {
+			channel /= 12.92
+		} else {
+			channel = math.Pow((channel+0.055)/1.055, 2.4)
+		}
+		luminance += weight * channel
+	}
+
+	// Contrast with white is 1.05/(L+0.05) and with black (L+0.05)/0.05;
+	// they are equal at L ≈ 0.179.
+	if luminance > 0.179 {
+		return "#000000"
+	}
+	return "#ffffff"
+}
@@ -0,0 +1,30 @@
+package wikilink
+
+import (
+	"regexp"
+	"strings"
+)
+
+// linkPattern matches [[target]] and [[target|label]].
+var linkPattern = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+
+// Targets returns the distinct link targets in content, a note title or id
+// each, in the order they first appear.
+func Targets(content string) []string {
+	var targets []string
+	seen := make(map[string]bool)
+
+	for _, match := range linkPattern.FindAllStringSubmatch(content, -1) {
+		target, _, _ := strings.Cut(match[1], "|")
+		target = strings.TrimSpace(target)
+
+		key := strings.ToLower(target)
+		if target == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		targets = append(targets, target)
+	}
+
+	return targets
+}